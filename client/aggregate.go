@@ -0,0 +1,86 @@
+// Server-side aggregation of numeric values. An entry may carry the Paillier ciphertext of its numeric
+// value, and the storage multiplies the ciphertexts of the entries matching a key token, which yields
+// the ciphertext of their sum. The Paillier key of an author is derived from the EKey returned by KDC,
+// and only its modulus is given to the storage, so that the storage learns nothing but the count.
+// Deriving the key searches for two 1024-bit primes, so it is done once by HEKey and the key is
+// passed to the other functions.
+
+package client
+
+import (
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"math/big"
+)
+
+var ErrNoHE = errors.New("entry has no homomorphic ciphertext")
+
+// HEKey derives the Paillier key of the author from its sub keys. It is slow, so the key should be
+// derived once and kept along with the sub keys
+func HEKey(keys *kdc.SubKey) (*crypto.PaillierPrivateKey, error) {
+	seed := crypto.DeriveKey(keys.EKey, []byte("paillier"), 32)
+	sk, err := crypto.GeneratePaillierKey(seed, crypto.PaillierBits)
+	if err != nil {
+		return nil, fmt.Errorf("HEKey: failed to generate key with error: %s", err.Error())
+	}
+	return sk, nil
+}
+
+// HEModulus returns the modulus of the Paillier key, which is given to the storage for aggregation
+func HEModulus(sk *crypto.PaillierPrivateKey) []byte {
+	return sk.N.Bytes()
+}
+
+// EncryptKeyValueWithHE encrypts key-value pair as EncryptKeyValue,
+// and also generates the Paillier ciphertext of its numeric value n by the key from HEKey
+func EncryptKeyValueWithHE(keys *kdc.SubKey, sk *crypto.PaillierPrivateKey, fileid, pub []byte,
+	kv *KeyValue, n uint64) (ekv *EnKeyValue, err error) {
+
+	ekv, err = EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithHE: %s", err.Error())
+	}
+
+	ekv.HE, err = sk.Encrypt(new(big.Int).SetUint64(n))
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithHE: failed to encrypt value with error: %s", err.Error())
+	}
+	return
+}
+
+// Aggregate returns the ciphertext of the sum of the numeric values of the entries matching the token,
+// along with the number of matched entries. It runs on storage side with the modulus of the author
+func Aggregate(modulus []byte, token *KeyToken, ekvs []*EnKeyValue) (sum []byte, count int, err error) {
+	pk, err := crypto.NewPaillierPublicKey(modulus)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var cts [][]byte
+	for _, ekv := range ekvs {
+		if !token.Match(ekv) {
+			continue
+		}
+		if ekv.HE == nil {
+			return nil, 0, ErrNoHE
+		}
+		cts = append(cts, ekv.HE)
+	}
+
+	sum, err = pk.Add(cts...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("Aggregate: failed to add ciphertexts with error: %s", err.Error())
+	}
+	return sum, len(cts), nil
+}
+
+// DecryptSum decrypts the aggregated ciphertext returned by the storage by the key from HEKey
+func DecryptSum(sk *crypto.PaillierPrivateKey, sum []byte) (*big.Int, error) {
+	m, err := sk.Decrypt(sum)
+	if err != nil {
+		return nil, fmt.Errorf("DecryptSum: failed to decrypt sum with error: %s", err.Error())
+	}
+	return m, nil
+}
@@ -0,0 +1,66 @@
+package client
+
+import (
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestAggregate(t *testing.T) {
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	entries := []struct {
+		key string
+		n   uint64
+	}{
+		{"order.1.amount", 100},
+		{"order.2.amount", 250},
+		{"order.3.amount", 7},
+		{"refund.1.amount", 40},
+	}
+
+	sk, err := HEKey(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ekvs []*EnKeyValue
+	for _, e := range entries {
+		ekv, err := EncryptKeyValueWithHE(keys, sk, fileid, pub, &KeyValue{Key: []byte(e.key), Value: []byte("n")}, e.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the ciphertext survives the record
+		buf, _ := MarshalKeyValue(ekv, pub, 0)
+		ekv, _, _, err = UnmarshalKeyValue(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ekvs = append(ekvs, ekv)
+	}
+
+	modulus := HEModulus(sk)
+	token, _ := KeyTrapdoor(keys.SKey, []byte("order.*"))
+	sum, count, err := Aggregate(modulus, token, ekvs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("aggregated %d entries, want 3", count)
+	}
+
+	total, err := DecryptSum(sk, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total.Uint64() != 357 {
+		t.Errorf("sum is %s, want 357", total)
+	}
+}
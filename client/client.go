@@ -3,6 +3,7 @@ package client
 import (
 	"bytes"
 	"crypto/ecdsa"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"genaro-crypto/crypto"
@@ -24,9 +25,17 @@ type KeyValue struct {
 
 // The ciphertext form of key-value pair
 type EnKeyValue struct {
-	SSEKey []byte // searchable ciphertext of key
-	EKey   []byte // ciphertext of key
-	EValue []byte // ciphertext of value
+	SSEKey    []byte   // searchable ciphertext of key
+	SSEPrefix [][]byte // searchable ciphertexts of key path prefixes
+	SSEValue  []byte   // searchable ciphertext of value, which is optional
+	SSEFuzzy  [][]byte // fuzzy tags of key, which are optional
+	SSEMulti  []byte   // multi-user tag of key, which is optional
+	ORE       []byte   // ORE ciphertext of numeric value, which is optional
+	HE        []byte   // Paillier ciphertext of numeric value, which is optional
+	EPath     [][]byte // encrypted segments of key path, which are optional
+	SSEConj   []byte   // conjunctive tags of key and value, which are optional
+	EKey      []byte   // ciphertext of key
+	EValue    []byte   // ciphertext of value
 }
 
 // LoadAsyKey initializes user by loading two keys from local file
@@ -42,14 +51,42 @@ func (user *GenaroUser) LoadAsyKey(ecdsapath, eciespath string) (err error) {
 	return nil
 }
 
-// EncryptKeyValue encrypts key-value pair by symmetrical keys from kdc
-func EncryptKeyValue(keys *kdc.SubKey, kv *KeyValue) (ekv *EnKeyValue, err error) {
-	ekey, err := crypto.AESEncryptCBC(keys.EKey, kv.Key)
+// LoadAsyKeyWithPass initializes user by loading two keys from local file, which
+// may be encrypted by passphrase or not
+func (user *GenaroUser) LoadAsyKeyWithPass(ecdsapath, eciespath, passphrase string) (err error) {
+	user.Spri, err = crypto.LoadEcdsaKeyFromFileWithPass(ecdsapath, passphrase)
+	if err != nil {
+		return err
+	}
+	user.Epri, err = crypto.LoadEciesKeyFromFileWithPass(eciespath, passphrase)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// associatedData joins the parts with 4-byte length prefixes, so that the parts cannot be shifted
+func associatedData(label string, parts ...[]byte) []byte {
+	ad := []byte(label)
+	for _, p := range parts {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(p)))
+		ad = append(ad, size[:]...)
+		ad = append(ad, p...)
+	}
+	return ad
+}
+
+// EncryptKeyValue encrypts key-value pair by symmetrical keys from kdc. Both ciphertexts are bound
+// to the fileid and the public key of author, and the ciphertext of value is also bound to the key,
+// so that they cannot be moved to another contract, author or key without being detected
+func EncryptKeyValue(keys *kdc.SubKey, fileid, pub []byte, kv *KeyValue) (ekv *EnKeyValue, err error) {
+	ekey, err := crypto.EncryptAEAD(keys.EKey, kv.Key, associatedData("key", fileid, pub))
 	if err != nil {
 		return nil, fmt.Errorf("EncryptKeyValue: failed to encrypt key with error: %s", err.Error())
 	}
 
-	evalue, err := crypto.AESEncryptCBC(keys.EKey, kv.Value)
+	evalue, err := crypto.EncryptAEAD(keys.EKey, kv.Value, associatedData("value", fileid, pub, kv.Key))
 	if err != nil {
 		return nil, fmt.Errorf("EncryptKeyValue: failed to encrypt value with error: %s", err.Error())
 	}
@@ -59,24 +96,78 @@ func EncryptKeyValue(keys *kdc.SubKey, kv *KeyValue) (ekv *EnKeyValue, err error
 		return nil, fmt.Errorf("EncryptKeyValue: failed to generate searchable ciphertext with error: %s", err.Error())
 	}
 
+	var sseprefix [][]byte
+	for _, prefix := range PathPrefixes(kv.Key) {
+		ssep, err := crypto.SearchableEnc(prefix, keys.SKey)
+		if err != nil {
+			return nil, fmt.Errorf("EncryptKeyValue: failed to generate searchable ciphertext of prefix with error: %s", err.Error())
+		}
+		sseprefix = append(sseprefix, ssep)
+	}
+
 	ekv = &EnKeyValue{
-		SSEKey: ssekey,
-		EKey:   ekey,
-		EValue: evalue,
+		SSEKey:    ssekey,
+		SSEPrefix: sseprefix,
+		EKey:      ekey,
+		EValue:    evalue,
 	}
 	return
 }
 
-// DecryptKeyValue decrypts ciphertext of key-value pair, and ignores the searchable ciphertext
-func DecryptKeyValue(keys *kdc.SubKey, ekv *EnKeyValue) (kv *KeyValue, err error) {
-	key, err := crypto.AESDecryptCBC(keys.EKey, ekv.EKey)
+// EncryptKeyValueWithSSEValue encrypts key-value pair as EncryptKeyValue,
+// and also generates the searchable ciphertext of value
+func EncryptKeyValueWithSSEValue(keys *kdc.SubKey, fileid, pub []byte, kv *KeyValue) (ekv *EnKeyValue, err error) {
+	ekv, err = EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		return nil, err
+	}
+
+	ekv.SSEValue, err = crypto.SearchableValueEnc(kv.Value, keys.SKey)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithSSEValue: failed to generate searchable ciphertext of value with error: %s", err.Error())
+	}
+	return
+}
+
+// MatchValue judges whether the value of the encrypted key-value pair matches the value token
+func MatchValue(token []byte, ekv *EnKeyValue) bool {
+	return ekv.SSEValue != nil && crypto.Matching(token, ekv.SSEValue)
+}
+
+// DecryptKeyValue decrypts ciphertext of key-value pair, and ignores the searchable ciphertext.
+// Legacy CBC ciphertexts are rejected, see DecryptKeyValueLegacy
+func DecryptKeyValue(keys *kdc.SubKey, fileid, pub []byte, ekv *EnKeyValue) (kv *KeyValue, err error) {
+	key, err := crypto.DecryptAEAD(keys.EKey, ekv.EKey, associatedData("key", fileid, pub))
 	if err != nil {
 		return nil, fmt.Errorf("DecryptKeyValue: failed to decrypt key with error: %s", err.Error())
 	}
 
+	value, err := DecryptValue(keys, fileid, pub, key, ekv.EValue)
+	if err != nil {
+		return nil, err
+	}
+
+	kv = &KeyValue{
+		Key:   key,
+		Value: value,
+	}
+	return
+}
+
+// DecryptKeyValueLegacy decrypts the legacy CBC ciphertext of key-value pair made by former
+// versions. It is not authenticated, so it should only be used for data from a trusted source
+func DecryptKeyValueLegacy(keys *kdc.SubKey, ekv *EnKeyValue) (kv *KeyValue, err error) {
+	if crypto.IsVersioned(ekv.EKey) || crypto.IsVersioned(ekv.EValue) {
+		return nil, errors.New("DecryptKeyValueLegacy: not a legacy ciphertext")
+	}
+
+	key, err := crypto.AESDecryptCBC(keys.EKey, ekv.EKey)
+	if err != nil {
+		return nil, fmt.Errorf("DecryptKeyValueLegacy: failed to decrypt key with error: %s", err.Error())
+	}
 	value, err := crypto.AESDecryptCBC(keys.EKey, ekv.EValue)
 	if err != nil {
-		return nil, fmt.Errorf("DecryptKeyValue: failed to decrypt value with error: %s", err.Error())
+		return nil, fmt.Errorf("DecryptKeyValueLegacy: failed to decrypt value with error: %s", err.Error())
 	}
 
 	kv = &KeyValue{
@@ -86,6 +177,15 @@ func DecryptKeyValue(keys *kdc.SubKey, ekv *EnKeyValue) (kv *KeyValue, err error
 	return
 }
 
+// DecryptValue decrypts ciphertext of value, whose plaintext key is known
+func DecryptValue(keys *kdc.SubKey, fileid, pub, key, evalue []byte) ([]byte, error) {
+	value, err := crypto.DecryptAEAD(keys.EKey, evalue, associatedData("value", fileid, pub, key))
+	if err != nil {
+		return nil, fmt.Errorf("DecryptValue: failed to decrypt value with error: %s", err.Error())
+	}
+	return value, nil
+}
+
 // GetResponseA handles the response of Request A
 func (user *GenaroUser) GetResponseA(rep []byte, path string,
 	pub *ecdsa.PublicKey,
@@ -149,9 +249,13 @@ func (user *GenaroUser) GetResponseB(rep, fileid []byte, pub *ecdsa.PublicKey,
 		return nil, nil, errors.New("GetResponseB: failed to unmarshal response-buffer")
 	}
 
-	msg := make([]byte, 1+len(rp.Cora))
+	// the keys are signed along in the path keys response, and empty otherwise
+	eks := kdc.EkeysToBytes(rp.Keys)
+
+	msg := make([]byte, 1+len(rp.Cora)+len(eks))
 	copy(msg, rp.Type)
 	copy(msg[1:], rp.Cora)
+	copy(msg[1+len(rp.Cora):], eks)
 
 	// Verify Signature
 	if !crypto.VerifySignature(msg, rp.Smsg, pub) {
@@ -163,6 +267,11 @@ func (user *GenaroUser) GetResponseB(rep, fileid []byte, pub *ecdsa.PublicKey,
 		return rp.Cora, nil, nil
 	}
 
+	// a maintainer limited to subtrees gets path keys instead
+	if bytes.Equal(rp.Type, []byte{0xbc}) {
+		return nil, nil, ErrScopedKeys
+	}
+
 	if !bytes.Equal(rp.Type, []byte{0Xab}) {
 		return nil, nil, errors.New("GetResponseB: wrong response-buffer")
 	}
@@ -267,3 +376,37 @@ func (user *GenaroUser) GetResponseE(rep, fileid []byte, pub *ecdsa.PublicKey,
 	}
 	return nil, keys, nil
 }
+
+// GetResponseF handles the response of Request F, and returns the signed snapshot buffer
+// which can be handed to storage nodes as it is
+func (user *GenaroUser) GetResponseF(rep, fileid []byte, pub *ecdsa.PublicKey,
+) (ans, snap []byte, err error) {
+	rp := &protobuf.Response{}
+	err = proto.Unmarshal(rep, rp)
+	if err != nil {
+		return nil, nil, errors.New("GetResponseF: failed to unmarshal response-buffer")
+	}
+
+	// Return the reason why kdc rejected
+	if bytes.Equal(rp.Type, []byte{0X00}) {
+		msg := make([]byte, 1+len(rp.Cora))
+		copy(msg, rp.Type)
+		copy(msg[1:], rp.Cora)
+
+		// Verify Signature
+		if !crypto.VerifySignature(msg, rp.Smsg, pub) {
+			return nil, nil, errors.New("GetResponseF: failed to verify signature")
+		}
+		return rp.Cora, nil, nil
+	}
+
+	s, err := VerifySnapshot(rep, pub)
+	if err != nil {
+		return nil, nil, fmt.Errorf("GetResponseF: %s", err.Error())
+	}
+
+	if !bytes.Equal(fileid, s.Fileid) {
+		return nil, nil, errors.New("GetResponseF: not wanted fileid")
+	}
+	return nil, rep, nil
+}
@@ -0,0 +1,51 @@
+// Conjunctive search of key-value pairs. The key and the value of a pair are tagged as two fields by
+// crypto.ConjunctiveTags, and a query token covers the key, the value or both of them. The storage
+// returns the entries matching the whole query, without learning which of the two fields match.
+
+package client
+
+import (
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+)
+
+// Fields of conjunctive tags
+const (
+	FieldKey   = 0
+	FieldValue = 1
+)
+
+// EncryptKeyValueWithConjunctive encrypts key-value pair as EncryptKeyValue,
+// and also generates the conjunctive tags of key and value
+func EncryptKeyValueWithConjunctive(keys *kdc.SubKey, fileid, pub []byte, kv *KeyValue) (ekv *EnKeyValue, err error) {
+	ekv, err = EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		return nil, err
+	}
+
+	ekv.SSEConj, err = crypto.ConjunctiveTags(keys.SKey, kv.Key, kv.Value)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithConjunctive: failed to generate conjunctive tags with error: %s", err.Error())
+	}
+	return
+}
+
+// ConjunctiveTrapdoor generates the token of the key and the value, and a nil
+// one is left out of the query
+func ConjunctiveTrapdoor(skey, key, value []byte) (*crypto.ConjunctiveToken, error) {
+	query := make(map[int][]byte)
+	if key != nil {
+		query[FieldKey] = key
+	}
+	if value != nil {
+		query[FieldValue] = value
+	}
+	return crypto.ConjunctiveTrapdoor(skey, query)
+}
+
+// MatchConjunctive judges whether the encrypted key-value pair matches every keyword
+// of the token, it runs on storage side
+func MatchConjunctive(token *crypto.ConjunctiveToken, ekv *EnKeyValue) bool {
+	return crypto.MatchAll(token, ekv.SSEConj)
+}
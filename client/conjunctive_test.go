@@ -0,0 +1,82 @@
+package client
+
+import (
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestConjunctiveSearch(t *testing.T) {
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	// the records are persisted and parsed back as storage does
+	var records [][]byte
+	for _, kv := range []*KeyValue{
+		{Key: []byte("dependencies.vue"), Value: []byte("^2.5.16")},
+		{Key: []byte("dependencies.vuex"), Value: []byte("^2.5.16")},
+		{Key: []byte("dependencies.vue"), Value: []byte("^2.4.0")},
+	} {
+		ekv, err := EncryptKeyValueWithConjunctive(keys, fileid, pub, kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec, err := MarshalKeyValue(ekv, pub, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+
+	search := func(key, value []byte) []*KeyValue {
+		token, err := ConjunctiveTrapdoor(keys.SKey, key, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kvs []*KeyValue
+		for _, rec := range records {
+			ekv, _, _, err := UnmarshalKeyValue(rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if MatchConjunctive(token, ekv) {
+				kv, err := DecryptKeyValue(keys, fileid, pub, ekv)
+				if err != nil {
+					t.Fatal(err)
+				}
+				kvs = append(kvs, kv)
+			}
+		}
+		return kvs
+	}
+
+	got := search([]byte("dependencies.vue"), []byte("^2.5.16"))
+	if len(got) != 1 || string(got[0].Key) != "dependencies.vue" || string(got[0].Value) != "^2.5.16" {
+		t.Errorf("conjunction of key and value mismatch")
+	}
+	if got = search(nil, []byte("^2.5.16")); len(got) != 2 {
+		t.Errorf("value matches %d entries, want 2", len(got))
+	}
+	if got = search([]byte("dependencies.vue"), nil); len(got) != 2 {
+		t.Errorf("key matches %d entries, want 2", len(got))
+	}
+	if got = search([]byte("dependencies.vuex"), []byte("^2.4.0")); len(got) != 0 {
+		t.Errorf("conjunction matches %d entries, want 0", len(got))
+	}
+
+	if _, err := ConjunctiveTrapdoor(keys.SKey, nil, nil); err != crypto.ErrNoKeyword {
+		t.Errorf("empty query should fail")
+	}
+
+	// a pair without conjunctive tags matches nothing
+	ekv, _ := EncryptKeyValue(keys, fileid, pub, &KeyValue{Key: []byte("dependencies.vue"), Value: []byte("^2.5.16")})
+	token, _ := ConjunctiveTrapdoor(keys.SKey, []byte("dependencies.vue"), nil)
+	if MatchConjunctive(token, ekv) {
+		t.Errorf("pair without tags matches")
+	}
+}
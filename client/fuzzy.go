@@ -0,0 +1,49 @@
+// Fuzzy search of keys. The fuzzy tags of a key cover the edit distance chosen when it is encrypted,
+// and a query token is generated with the same distance. The storage returns the candidates whose tags
+// intersect the token, and the client keeps those within the tolerance after decrypting them.
+
+package client
+
+import (
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+)
+
+// EncryptKeyValueWithFuzzy encrypts key-value pair as EncryptKeyValue,
+// and also generates the fuzzy tags of key with the edit distance
+func EncryptKeyValueWithFuzzy(keys *kdc.SubKey, fileid, pub []byte, kv *KeyValue, distance int) (ekv *EnKeyValue, err error) {
+	ekv, err = EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		return nil, err
+	}
+
+	ekv.SSEFuzzy, err = crypto.FuzzyTags(keys.SKey, kv.Key, distance)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithFuzzy: failed to generate fuzzy tags with error: %s", err.Error())
+	}
+	return
+}
+
+// FuzzyTrapdoor generates the fuzzy token of the query, and the distance
+// should be the same as the one of fuzzy tags
+func FuzzyTrapdoor(skey, query []byte, distance int) ([][]byte, error) {
+	return crypto.FuzzyTags(skey, query, distance)
+}
+
+// MatchFuzzyKey judges whether the key of the encrypted key-value pair may be close to the
+// query of the token, it runs on storage side
+func MatchFuzzyKey(token [][]byte, ekv *EnKeyValue) bool {
+	return crypto.MatchFuzzy(token, ekv.SSEFuzzy)
+}
+
+// FilterFuzzy keeps the decrypted candidates whose keys are within the tolerance of the query
+func FilterFuzzy(query []byte, tolerance int, kvs []*KeyValue) []*KeyValue {
+	var out []*KeyValue
+	for _, kv := range kvs {
+		if crypto.EditDistance(query, kv.Key) <= tolerance {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestFuzzySearch(t *testing.T) {
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	var ekvs []*EnKeyValue
+	for _, key := range []string{"scripts.build", "scripts.dev", "version"} {
+		ekv, err := EncryptKeyValueWithFuzzy(keys, fileid, pub, &KeyValue{Key: []byte(key), Value: []byte("v")}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ekvs = append(ekvs, ekv)
+	}
+
+	query := []byte("scripts.buidl")
+	token, err := FuzzyTrapdoor(keys.SKey, query, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var kvs []*KeyValue
+	for _, ekv := range ekvs {
+		if MatchFuzzyKey(token, ekv) {
+			kv, err := DecryptKeyValue(keys, fileid, pub, ekv)
+			if err != nil {
+				t.Fatal(err)
+			}
+			kvs = append(kvs, kv)
+		}
+	}
+
+	if got := FilterFuzzy(query, 1, kvs); len(got) != 0 {
+		t.Errorf("tolerance 1 should match nothing, got %d", len(got))
+	}
+	got := FilterFuzzy(query, 2, kvs)
+	if len(got) != 1 || string(got[0].Key) != "scripts.build" {
+		t.Errorf("tolerance 2 should match scripts.build")
+	}
+}
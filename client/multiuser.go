@@ -0,0 +1,105 @@
+// Multi-user search of a contract. Every writer tags its keys with crypto.MUTag, and a maintainer
+// searches all of them with one token from its own SKey. The maintainer calls for the deltas by
+// RequestG and hands the response to the storage node, which decrypts the deltas, converts the
+// token for each writer and matches the entries of that writer.
+
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// Delta converts the tokens of a reader into the ones of the writer
+type Delta struct {
+	Pub, Delta []byte
+}
+
+// EncryptKeyValueWithMultiUser encrypts key-value pair as EncryptKeyValue,
+// and also generates the multi-user tag of key
+func EncryptKeyValueWithMultiUser(keys *kdc.SubKey, fileid, pub []byte, kv *KeyValue) (ekv *EnKeyValue, err error) {
+	ekv, err = EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		return nil, err
+	}
+
+	ekv.SSEMulti, err = crypto.MUTag(keys.SKey, kv.Key)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithMultiUser: failed to generate multi-user tag with error: %s", err.Error())
+	}
+	return
+}
+
+// GetResponseG handles the response of Request G on the storage node, whose ecies key
+// is the Epri of user, and returns the deltas of every writer of the contract
+func (user *GenaroUser) GetResponseG(rep, fileid []byte, pub *ecdsa.PublicKey,
+) (ans []byte, deltas []*Delta, err error) {
+	rp := &protobuf.Response{}
+	err = proto.Unmarshal(rep, rp)
+	if err != nil {
+		return nil, nil, errors.New("GetResponseG: failed to unmarshal response-buffer")
+	}
+
+	eds := kdc.EkeysToBytes(rp.Keys)
+
+	msg := make([]byte, 1+len(rp.Cora)+len(eds))
+	copy(msg, rp.Type)
+	copy(msg[1:], rp.Cora)
+	copy(msg[1+len(rp.Cora):], eds)
+
+	// Verify Signature
+	if !crypto.VerifySignature(msg, rp.Smsg, pub) {
+		return nil, nil, errors.New("GetResponseG: failed to verify signature")
+	}
+
+	// Return the reason why kdc rejected
+	if bytes.Equal(rp.Type, []byte{0x00}) {
+		return rp.Cora, nil, nil
+	}
+
+	if !bytes.Equal(rp.Type, []byte{0xde}) {
+		return nil, nil, errors.New("GetResponseG: wrong response-buffer")
+	}
+
+	if !bytes.Equal(fileid, rp.Cora) {
+		return nil, nil, errors.New("GetResponseG: not wanted fileid")
+	}
+
+	for _, ko := range rp.Keys {
+		d, err := crypto.EciesDecrypt(ko.Enk, user.Epri)
+		if err != nil {
+			return nil, nil, errors.New("GetResponseG: something wrong with decryption")
+		}
+		deltas = append(deltas, &Delta{Pub: ko.Pub, Delta: d})
+	}
+	return nil, deltas, nil
+}
+
+// ConvertToken converts the token of reader for every writer, and the
+// converted tokens are indexed by the hex string of writer's pub
+func ConvertToken(token []byte, deltas []*Delta) (map[string][]byte, error) {
+	converted := make(map[string][]byte, len(deltas))
+	for _, d := range deltas {
+		c, err := crypto.MUConvert(token, d.Delta)
+		if err != nil {
+			return nil, err
+		}
+		converted[hex.EncodeToString(d.Pub)] = c
+	}
+	return converted, nil
+}
+
+// MatchMultiUser judges whether the encrypted key-value pair written by pub matches the
+// converted tokens, it runs on storage side
+func MatchMultiUser(converted map[string][]byte, pub []byte, ekv *EnKeyValue) bool {
+	c, ok := converted[hex.EncodeToString(pub)]
+	return ok && crypto.MUMatch(c, ekv.SSEMulti)
+}
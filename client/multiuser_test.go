@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestMultiUserSearch(t *testing.T) {
+	fileid := crypto.SaltGen()
+	msk := crypto.KeyGen()
+	newKeys := func() *kdc.SubKey {
+		return &kdc.SubKey{
+			EKey: crypto.KeyDerivFunc(msk, crypto.SaltGen(), crypto.EKeyLen),
+			SKey: crypto.KeyDerivFunc(msk, crypto.SaltGen(), crypto.SKeyLen),
+		}
+	}
+
+	// two writers and a reader of the contract
+	pubs := [][]byte{[]byte("writer A"), []byte("writer B")}
+	writers := []*kdc.SubKey{newKeys(), newKeys()}
+	reader := newKeys()
+
+	type entry struct {
+		pub []byte
+		ekv *EnKeyValue
+	}
+	var entries []entry
+	for i, keys := range writers {
+		for _, key := range []string{"name", "version"} {
+			ekv, err := EncryptKeyValueWithMultiUser(keys, fileid, pubs[i], &KeyValue{Key: []byte(key), Value: []byte("v")})
+			if err != nil {
+				t.Fatal(err)
+			}
+			entries = append(entries, entry{pubs[i], ekv})
+		}
+	}
+
+	// deltas supplied by kdc
+	var deltas []*Delta
+	for i, keys := range writers {
+		deltas = append(deltas, &Delta{Pub: pubs[i], Delta: crypto.MUDelta(reader.SKey, keys.SKey)})
+	}
+
+	converted, err := ConvertToken(crypto.MUTrapdoor(reader.SKey, []byte("name")), deltas)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	num := 0
+	for _, e := range entries {
+		if MatchMultiUser(converted, e.pub, e.ekv) {
+			num++
+		}
+	}
+	if num != 2 {
+		t.Errorf("token matches %d entries, want 2", num)
+	}
+
+	// entries of an unknown writer never match
+	if MatchMultiUser(converted, []byte(hex.EncodeToString([]byte("C"))), entries[0].ekv) {
+		t.Errorf("unknown writer should not match")
+	}
+}
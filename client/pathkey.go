@@ -0,0 +1,213 @@
+// A maintainer limited to subtrees of the document gets the node keys of its path prefixes by
+// CallRequestBWithPaths, and every key path under a prefix has its own sub keys derived from them
+// (see kdc/pathkey.go). The entries carry the encrypted segments of their key paths, so that the
+// holder of any prefix above an entry can recover its key path, derive its sub keys and decrypt it.
+// A prefix ciphertext "a.b.*" is made by the sub keys of "a.b", so a prefix query is answered by
+// the holders of "a.b" or above, while a writer cannot make the ones above its own prefix.
+
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var ErrScopedKeys = errors.New("kdc responded path keys of a scoped maintainer, which are handled by GetResponsePaths")
+
+// PathKey is the node key of a path prefix along with the encrypted segments of the prefix
+type PathKey struct {
+	Node   []byte
+	Header [][]byte
+}
+
+// PathKeys are the granted path keys indexed by prefix, and the root is ""
+type PathKeys map[string]*PathKey
+
+// GetResponsePaths handles the response of Request B for path keys
+func (user *GenaroUser) GetResponsePaths(rep, fileid []byte, pub *ecdsa.PublicKey,
+) (ans []byte, keys PathKeys, err error) {
+	rp := &protobuf.Response{}
+	err = proto.Unmarshal(rep, rp)
+	if err != nil {
+		return nil, nil, errors.New("GetResponsePaths: failed to unmarshal response-buffer")
+	}
+
+	eks := kdc.EkeysToBytes(rp.Keys)
+
+	msg := make([]byte, 1+len(rp.Cora)+len(eks))
+	copy(msg, rp.Type)
+	copy(msg[1:], rp.Cora)
+	copy(msg[1+len(rp.Cora):], eks)
+
+	// Verify Signature
+	if !crypto.VerifySignature(msg, rp.Smsg, pub) {
+		return nil, nil, errors.New("GetResponsePaths: failed to verify signature")
+	}
+
+	// Return the reason why kdc rejected
+	if bytes.Equal(rp.Type, []byte{0x00}) {
+		return rp.Cora, nil, nil
+	}
+
+	if !bytes.Equal(rp.Type, []byte{0xbc}) {
+		return nil, nil, errors.New("GetResponsePaths: wrong response-buffer")
+	}
+
+	if !bytes.Equal(fileid, rp.Cora) {
+		return nil, nil, errors.New("GetResponsePaths: not wanted fileid")
+	}
+
+	keys = make(PathKeys, len(rp.Keys))
+	for _, ko := range rp.Keys {
+		m, err := crypto.EciesDecrypt(ko.Enk, user.Epri)
+		if err != nil {
+			return nil, nil, errors.New("GetResponsePaths: something wrong with decryption")
+		}
+
+		// m = node||len||segment||len||segment...
+		if len(m) < kdc.PathKeyLen {
+			return nil, nil, errors.New("GetResponsePaths: wrong path key")
+		}
+		pk := &PathKey{Node: m[:kdc.PathKeyLen]}
+		for rest := m[kdc.PathKeyLen:]; len(rest) > 0; {
+			if len(rest) < 4 || uint64(len(rest)-4) < uint64(binary.BigEndian.Uint32(rest)) {
+				return nil, nil, errors.New("GetResponsePaths: wrong path header")
+			}
+			n := 4 + int(binary.BigEndian.Uint32(rest))
+			pk.Header = append(pk.Header, rest[4:n])
+			rest = rest[n:]
+		}
+		keys[string(ko.Pub)] = pk
+	}
+	return nil, keys, nil
+}
+
+// scope returns the longest granted prefix of the key path
+func (pk PathKeys) scope(path string) (prefix string, key *PathKey, err error) {
+	for p, k := range pk {
+		if kdc.InScope(path, p) && (key == nil || len(p) > len(prefix)) {
+			prefix, key = p, k
+		}
+	}
+	if key == nil {
+		return "", nil, kdc.ErrOutOfScope
+	}
+	return
+}
+
+// SubKey derives the sub keys of the key path from the granted prefix
+func (pk PathKeys) SubKey(path string) (*kdc.SubKey, error) {
+	prefix, key, err := pk.scope(path)
+	if err != nil {
+		return nil, err
+	}
+
+	node := key.Node
+	for _, s := range kdc.PathSegments(path)[len(kdc.PathSegments(prefix)):] {
+		node = kdc.ChildKey(node, s)
+	}
+	return kdc.PathSubKey(node), nil
+}
+
+// EncryptKeyValueInPath encrypts key-value pair as EncryptKeyValue by the sub keys of its key path,
+// along with the encrypted segments of the key path
+func EncryptKeyValueInPath(pk PathKeys, fileid, pub []byte, kv *KeyValue) (ekv *EnKeyValue, err error) {
+	path := string(kv.Key)
+	segments := kdc.PathSegments(path)
+	for _, s := range segments {
+		if s == "" {
+			return nil, kdc.ErrBadPath
+		}
+	}
+
+	prefix, key, err := pk.scope(path)
+	if err != nil {
+		return nil, err
+	}
+	depth := len(kdc.PathSegments(prefix))
+
+	// encrypt the segments below the prefix, and keep the node keys of the prefixes
+	epath := append([][]byte{}, key.Header...)
+	nodes := [][]byte{key.Node}
+	for i := depth; i < len(segments); i++ {
+		node := nodes[len(nodes)-1]
+		es, err := kdc.EncryptSegment(node, fileid, i, segments[i])
+		if err != nil {
+			return nil, fmt.Errorf("EncryptKeyValueInPath: failed to encrypt segment with error: %s", err.Error())
+		}
+		epath = append(epath, es)
+		nodes = append(nodes, kdc.ChildKey(node, segments[i]))
+	}
+
+	ekv, err = EncryptKeyValue(kdc.PathSubKey(nodes[len(nodes)-1]), fileid, pub, kv)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueInPath: %s", err.Error())
+	}
+
+	// each prefix ciphertext is made by the sub keys of the prefix, if it is under the granted one
+	var sseprefix [][]byte
+	for i, p := range PathPrefixes(kv.Key) {
+		if i+1 < depth {
+			continue
+		}
+		ssep, err := crypto.SearchableEnc(p, kdc.PathSubKey(nodes[i+1-depth]).SKey)
+		if err != nil {
+			return nil, fmt.Errorf("EncryptKeyValueInPath: failed to generate searchable ciphertext of prefix with error: %s", err.Error())
+		}
+		sseprefix = append(sseprefix, ssep)
+	}
+	ekv.SSEPrefix = sseprefix
+	ekv.EPath = epath
+	return
+}
+
+// PathTrapdoor turns the query into a search token as KeyTrapdoor by the sub keys of its key path
+func PathTrapdoor(pk PathKeys, query []byte) (*KeyToken, error) {
+	path := strings.TrimSuffix(string(query), PathSeparator+PathWildcard)
+	keys, err := pk.SubKey(path)
+	if err != nil {
+		return nil, err
+	}
+	return KeyTrapdoor(keys.SKey, query)
+}
+
+// DecryptKeyValueInPath recovers the key path of the entry by any granted prefix above it,
+// and decrypts the entry by the sub keys of the key path
+func DecryptKeyValueInPath(pk PathKeys, fileid, pub []byte, ekv *EnKeyValue) (kv *KeyValue, err error) {
+	for prefix, key := range pk {
+		segments := kdc.PathSegments(prefix)
+		if len(ekv.EPath) < len(segments) {
+			continue
+		}
+
+		node := key.Node
+		for i := len(segments); i < len(ekv.EPath); i++ {
+			s, err := kdc.DecryptSegment(node, fileid, i, ekv.EPath[i])
+			if err != nil {
+				node = nil
+				break
+			}
+			segments = append(segments, s)
+			node = kdc.ChildKey(node, s)
+		}
+		if node == nil {
+			continue
+		}
+
+		kv, err = DecryptKeyValue(kdc.PathSubKey(node), fileid, pub, ekv)
+		if err != nil || string(kv.Key) != strings.Join(segments, kdc.PathSeparator) {
+			continue
+		}
+		return kv, nil
+	}
+	return nil, kdc.ErrOutOfScope
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// grant returns the path keys of the prefixes as kdc does
+func grant(msk, fileid []byte, prefixes ...string) PathKeys {
+	pk := make(PathKeys)
+	for _, p := range prefixes {
+		header, err := kdc.PathHeader(msk, fileid, p)
+		if err != nil {
+			panic(err)
+		}
+		pk[p] = &PathKey{Node: kdc.PathKey(msk, p), Header: header}
+	}
+	return pk
+}
+
+func TestPathKeys(t *testing.T) {
+	msk := crypto.KeyGen()
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	owner := grant(msk, fileid, "")
+	deps := grant(msk, fileid, "dependencies")
+	lodash := grant(msk, fileid, "dependencies.lodash")
+	scripts := grant(msk, fileid, "scripts")
+
+	// a maintainer cannot write beyond its scopes
+	if _, err := EncryptKeyValueInPath(deps, fileid, pub, &KeyValue{Key: []byte("scripts.build"), Value: []byte("make")}); err != kdc.ErrOutOfScope {
+		t.Errorf("writing out of scope should fail")
+	}
+
+	kvs := []*KeyValue{
+		{Key: []byte("dependencies.lodash.version"), Value: []byte("4.17.21")},
+		{Key: []byte("dependencies.react"), Value: []byte("18.2.0")},
+	}
+	var ekvs []*EnKeyValue
+	for _, kv := range kvs {
+		ekv, err := EncryptKeyValueInPath(deps, fileid, pub, kv)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the segments survive the record
+		buf, _ := MarshalKeyValue(ekv, pub, 0)
+		ekv, _, _, err = UnmarshalKeyValue(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ekvs = append(ekvs, ekv)
+	}
+	ekv, err := EncryptKeyValueInPath(owner, fileid, pub, &KeyValue{Key: []byte("scripts.build"), Value: []byte("make")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ekvs = append(ekvs, ekv)
+
+	// readers decrypt the entries under their prefixes only
+	readable := []struct {
+		name   string
+		keys   PathKeys
+		expect []bool
+	}{
+		{"owner", owner, []bool{true, true, true}},
+		{"dependencies", deps, []bool{true, true, false}},
+		{"dependencies.lodash", lodash, []bool{true, false, false}},
+		{"scripts", scripts, []bool{false, false, true}},
+	}
+	for _, r := range readable {
+		for i, ekv := range ekvs {
+			_, err := DecryptKeyValueInPath(r.keys, fileid, pub, ekv)
+			if (err == nil) != r.expect[i] {
+				t.Errorf("%s decrypting entry %d: %v", r.name, i, err)
+			}
+		}
+	}
+
+	kv, _ := DecryptKeyValueInPath(owner, fileid, pub, ekvs[0])
+	if string(kv.Key) != "dependencies.lodash.version" || string(kv.Value) != "4.17.21" {
+		t.Errorf("wrong key-value pair %s: %s", kv.Key, kv.Value)
+	}
+
+	// prefix queries of the owner and maintainers match the same entries
+	count := func(pk PathKeys, query string) int {
+		kt, err := PathTrapdoor(pk, []byte(query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, ekv := range ekvs {
+			if kt.Match(ekv) {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(owner, "dependencies.*"); n != 2 {
+		t.Errorf("owner matches %d, want 2", n)
+	}
+	if n := count(lodash, "dependencies.lodash.*"); n != 1 {
+		t.Errorf("lodash maintainer matches %d, want 1", n)
+	}
+	if n := count(scripts, "scripts.build"); n != 1 {
+		t.Errorf("scripts maintainer matches %d, want 1", n)
+	}
+	if _, err = PathTrapdoor(lodash, []byte("dependencies.*")); err != kdc.ErrOutOfScope {
+		t.Errorf("query out of scope should fail")
+	}
+}
+
+// pathKeysResponse plays the role of kdc to respond the path keys of the prefixes
+func pathKeysResponse(user *GenaroUser, kpri *ecdsa.PrivateKey, msk, fileid []byte, prefixes ...string) []byte {
+	var ras []*protobuf.ResponseAllkeys
+	for p, key := range grant(msk, fileid, prefixes...) {
+		m := append([]byte{}, key.Node...)
+		for _, es := range key.Header {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], uint32(len(es)))
+			m = append(append(m, size[:]...), es...)
+		}
+		ek, err := crypto.EciesEncrypt(rand.Reader, &user.Epri.PublicKey, m)
+		if err != nil {
+			panic(err)
+		}
+		ras = append(ras, &protobuf.ResponseAllkeys{Pub: []byte(p), Enk: ek})
+	}
+
+	msg := append(append([]byte{0xbc}, fileid...), kdc.EkeysToBytes(ras)...)
+	sign, err := crypto.SignMessage(msg, kpri)
+	if err != nil {
+		panic(err)
+	}
+	buf, _ := proto.Marshal(&protobuf.Response{
+		Type: []byte{0xbc},
+		Cora: fileid,
+		Keys: ras,
+		Smsg: sign,
+	})
+	return buf
+}
+
+func TestScopedResponseB(t *testing.T) {
+	user := new(GenaroUser)
+	err := user.LoadAsyKey("./testdata/ecdsaB", "./testdata/eciesB")
+	if err != nil {
+		panic(err)
+	}
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	msk := crypto.KeyGen()
+	fileid := crypto.SaltGen()
+	rep := pathKeysResponse(user, kpri, msk, fileid, "dependencies", "scripts.build")
+
+	// the path keys are only handled by GetResponsePaths
+	if _, _, err = user.GetResponseB(rep, fileid, &kpri.PublicKey); err != ErrScopedKeys {
+		t.Errorf("path keys response should point to GetResponsePaths, got %v", err)
+	}
+	_, keys, err := user.GetResponsePaths(rep, fileid, &kpri.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys["scripts.build"] == nil || len(keys["scripts.build"].Header) != 2 {
+		t.Errorf("wrong path keys")
+	}
+
+	// the keys are signed along
+	pb := &protobuf.Response{}
+	proto.Unmarshal(rep, pb)
+	pb.Keys[0].Pub = []byte("version")
+	tampered, _ := proto.Marshal(pb)
+	if _, _, err = user.GetResponseB(tampered, fileid, &kpri.PublicKey); err == nil || err == ErrScopedKeys {
+		t.Errorf("tampered path keys response passed the check")
+	}
+
+	// so the searcher reports the file of a scoped maintainer
+	source := &KDCKeySource{
+		User:   user,
+		KDCPub: &kpri.PublicKey,
+		Call:   func([]byte) ([]byte, error) { return rep, nil },
+	}
+	if _, err = source.Keys(fileid); err != ErrScopedKeys {
+		t.Errorf("key source should report the scoped maintainer, got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+// JSON key-values are flattened into dotted key paths, such as "scripts.build:mas". Besides the key
+// itself, every path prefix is encrypted as a keyword with the wildcard: "scripts.*". A query ending
+// with ".*" is a prefix query which matches the prefix ciphertexts, and any other query matches the
+// key ciphertext exactly. The prefixes can be put into crypto.BuildIndex in the same way.
+
+package client
+
+import (
+	"bytes"
+	"errors"
+	"genaro-crypto/crypto"
+)
+
+const (
+	PathSeparator = "."
+	PathWildcard  = "*"
+)
+
+var ErrBadQuery = errors.New("invalid key path query")
+
+// PathPrefixes returns the prefix keywords of a key path, "a.b.c" has "a.*" and "a.b.*"
+func PathPrefixes(key []byte) [][]byte {
+	var prefixes [][]byte
+	sep := []byte(PathSeparator)
+	for i := 0; i < len(key); {
+		j := bytes.Index(key[i:], sep)
+		if j < 0 {
+			break
+		}
+		i += j + len(sep)
+
+		prefix := make([]byte, 0, i+len(PathWildcard))
+		prefix = append(prefix, key[:i]...)
+		prefixes = append(prefixes, append(prefix, PathWildcard...))
+	}
+	return prefixes
+}
+
+// KeyToken is the search token of a key path query
+type KeyToken struct {
+	Token  []byte
+	Prefix bool
+}
+
+// KeyTrapdoor turns the query into a search token. A query "a.b.*" matches every key under "a.b",
+// and the wildcard elsewhere is taken literally
+func KeyTrapdoor(skey, query []byte) (*KeyToken, error) {
+	wildcard := []byte(PathSeparator + PathWildcard)
+	prefix := bytes.HasSuffix(query, wildcard)
+	if len(query) == 0 || (prefix && len(query) == len(wildcard)) {
+		return nil, ErrBadQuery
+	}
+
+	token, err := crypto.Trapdoor(query, skey)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyToken{
+		Token:  token,
+		Prefix: prefix,
+	}, nil
+}
+
+// Match reports whether the encrypted key-value pair matches the token, it runs on storage side
+func (kt *KeyToken) Match(ekv *EnKeyValue) bool {
+	if !kt.Prefix {
+		return crypto.Matching(kt.Token, ekv.SSEKey)
+	}
+	for _, ssep := range ekv.SSEPrefix {
+		if crypto.Matching(kt.Token, ssep) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestPathPrefixes(t *testing.T) {
+	prefixes := PathPrefixes([]byte("dependencies.@fortawesome.fontawesome"))
+	if len(prefixes) != 2 || string(prefixes[0]) != "dependencies.*" || string(prefixes[1]) != "dependencies.@fortawesome.*" {
+		t.Errorf("wrong prefixes %q", prefixes)
+	}
+	if len(PathPrefixes([]byte("name"))) != 0 {
+		t.Errorf("top-level key has no prefix")
+	}
+}
+
+func TestPrefixSearch(t *testing.T) {
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	var ekvs []*EnKeyValue
+	for _, key := range []string{"scripts.build:mas", "scripts.dev", "devDependencies.new.url-loader", "name"} {
+		ekv, err := EncryptKeyValue(keys, fileid, pub, &KeyValue{Key: []byte(key), Value: []byte("v")})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ekvs = append(ekvs, ekv)
+	}
+
+	count := func(query string) int {
+		token, err := KeyTrapdoor(keys.SKey, []byte(query))
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, ekv := range ekvs {
+			if token.Match(ekv) {
+				n++
+			}
+		}
+		return n
+	}
+
+	cases := map[string]int{
+		"scripts.*":             2,
+		"scripts.dev":           1,
+		"scripts":               0,
+		"devDependencies.*":     1,
+		"devDependencies.new.*": 1,
+		"name":                  1,
+		"name.*":                0,
+	}
+	for query, want := range cases {
+		if got := count(query); got != want {
+			t.Errorf("%s: %d matches, want %d", query, got, want)
+		}
+	}
+
+	if _, err := KeyTrapdoor(keys.SKey, []byte(".*")); err != ErrBadQuery {
+		t.Errorf("bare wildcard should fail")
+	}
+}
@@ -0,0 +1,61 @@
+// Range queries over numeric values, such as versions, amounts and timestamps. An entry may carry the
+// ORE ciphertext of its numeric value, and the storage compares it with the bounds of a range token.
+
+package client
+
+import (
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"math"
+)
+
+// RangeToken matches the numeric values in [Min, Max], a nil bound is unbounded
+type RangeToken struct {
+	Min, Max []byte
+}
+
+// EncryptKeyValueWithORE encrypts key-value pair as EncryptKeyValue,
+// and also generates the ORE ciphertext of its numeric value n
+func EncryptKeyValueWithORE(keys *kdc.SubKey, fileid, pub []byte, kv *KeyValue, n uint64) (ekv *EnKeyValue, err error) {
+	ekv, err = EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		return nil, fmt.Errorf("EncryptKeyValueWithORE: %s", err.Error())
+	}
+
+	ekv.ORE = crypto.OREEncrypt(crypto.OREKey(keys.SKey), n)
+	return
+}
+
+// RangeTrapdoor generates the token of the values in [min, max]. A query "amount > 1000"
+// is RangeTrapdoor(keys, 1001, math.MaxUint64)
+func RangeTrapdoor(keys *kdc.SubKey, min, max uint64) *RangeToken {
+	okey := crypto.OREKey(keys.SKey)
+
+	rt := new(RangeToken)
+	if min > 0 {
+		rt.Min = crypto.OREEncrypt(okey, min)
+	}
+	if max < math.MaxUint64 {
+		rt.Max = crypto.OREEncrypt(okey, max)
+	}
+	return rt
+}
+
+// Match judges whether the numeric value of the entry is in the range, it runs on storage side
+func (rt *RangeToken) Match(ekv *EnKeyValue) bool {
+	if len(ekv.ORE) != crypto.ORELen {
+		return false
+	}
+	if rt.Min != nil {
+		if c, err := crypto.ORECompare(ekv.ORE, rt.Min); err != nil || c < 0 {
+			return false
+		}
+	}
+	if rt.Max != nil {
+		if c, err := crypto.ORECompare(ekv.ORE, rt.Max); err != nil || c > 0 {
+			return false
+		}
+	}
+	return true
+}
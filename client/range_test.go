@@ -0,0 +1,55 @@
+package client
+
+import (
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"math"
+	"testing"
+)
+
+func TestRangeQuery(t *testing.T) {
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	amounts := []uint64{10, 999, 1000, 1001, 50000}
+	var ekvs []*EnKeyValue
+	for _, n := range amounts {
+		ekv, err := EncryptKeyValueWithORE(keys, fileid, pub, &KeyValue{Key: []byte("amount"), Value: []byte("n")}, n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the ciphertext survives the record
+		buf, _ := MarshalKeyValue(ekv, pub, 0)
+		ekv, _, _, err = UnmarshalKeyValue(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ekvs = append(ekvs, ekv)
+	}
+
+	count := func(rt *RangeToken) int {
+		n := 0
+		for _, ekv := range ekvs {
+			if rt.Match(ekv) {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := count(RangeTrapdoor(keys, 1001, math.MaxUint64)); n != 2 {
+		t.Errorf("amount > 1000 matches %d, want 2", n)
+	}
+	if n := count(RangeTrapdoor(keys, 0, 1000)); n != 3 {
+		t.Errorf("amount <= 1000 matches %d, want 3", n)
+	}
+	if n := count(RangeTrapdoor(keys, 999, 1001)); n != 3 {
+		t.Errorf("999 <= amount <= 1001 matches %d, want 3", n)
+	}
+}
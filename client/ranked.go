@@ -0,0 +1,105 @@
+// Ranked search over long text values. Every term of a value gets the relevance score (1+ln tf)/|value|
+// of Wang et al., which is encrypted by one-to-many OPE and stored along with the entry id in an encrypted
+// inverted index. The storage looks up the entries of a term token and returns the top-k of them by the
+// encrypted scores, and the client may decrypt the scores.
+
+package client
+
+import (
+	"bytes"
+	"encoding/binary"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"math"
+	"sort"
+	"unicode"
+)
+
+// RankedResult is an entry id along with its encrypted score
+type RankedResult struct {
+	ID    []byte
+	Score uint64
+}
+
+// Terms splits the text into lower case words, and returns the term frequencies and the number of words
+func Terms(text []byte) (tfs map[string]int, length int) {
+	tfs = make(map[string]int)
+	for _, w := range bytes.FieldsFunc(bytes.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tfs[string(w)]++
+		length++
+	}
+	return
+}
+
+// RelevanceScore returns the score of a term which appears tf times in a value of length words
+func RelevanceScore(tf, length int) uint16 {
+	if tf <= 0 || length <= 0 {
+		return 0
+	}
+	s := (1 + math.Log(float64(tf))) / float64(length)
+	if s >= 1 {
+		return math.MaxUint16
+	}
+	return uint16(s * math.MaxUint16)
+}
+
+func rankedKey(keys *kdc.SubKey) []byte {
+	return crypto.DeriveKey(keys.SKey, []byte("ranked"), crypto.SKeyLen)
+}
+
+// BuildRankedIndex builds the ranked index of the values, which are indexed by entry ids
+func BuildRankedIndex(keys *kdc.SubKey, values map[string][]byte) (crypto.EncryptedIndex, error) {
+	okey := crypto.OPEKey(keys.SKey)
+	index := make(map[string][][]byte)
+
+	for id, value := range values {
+		tfs, length := Terms(value)
+		for term, tf := range tfs {
+			score, err := crypto.OPEEncryptRandom(okey, RelevanceScore(tf, length))
+			if err != nil {
+				return nil, err
+			}
+
+			// id||score
+			ele := make([]byte, len(id)+8)
+			copy(ele, id)
+			binary.BigEndian.PutUint64(ele[len(id):], score)
+			index[term] = append(index[term], ele)
+		}
+	}
+	return crypto.BuildIndex(rankedKey(keys), index)
+}
+
+// RankedTrapdoor generates the token of a term
+func RankedTrapdoor(keys *kdc.SubKey, term []byte) *crypto.IndexToken {
+	return crypto.IndexTrapdoor(rankedKey(keys), bytes.ToLower(term))
+}
+
+// TopK returns the k entries with the highest scores for the token, it runs on storage side
+func TopK(lookup func(label []byte) ([]byte, bool), token *crypto.IndexToken, k int) []*RankedResult {
+	var results []*RankedResult
+	for _, ele := range crypto.SearchIndex(lookup, token) {
+		if len(ele) < 8 {
+			continue
+		}
+		results = append(results, &RankedResult{
+			ID:    ele[:len(ele)-8],
+			Score: binary.BigEndian.Uint64(ele[len(ele)-8:]),
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	if k >= 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+// DecryptScore returns the relevance score of the encrypted one
+func DecryptScore(keys *kdc.SubKey, score uint64) uint16 {
+	return crypto.OPEDecrypt(crypto.OPEKey(keys.SKey), score)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestRankedSearch(t *testing.T) {
+	keys := &kdc.SubKey{
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+
+	values := map[string][]byte{
+		"e1": []byte("Genaro is a blockchain. The blockchain stores encrypted contracts on the blockchain."),
+		"e2": []byte("Encrypted storage network with a blockchain"),
+		"e3": []byte("A long description of the public chain, whose blockchain keeps files, nodes, peers and many other things."),
+		"e4": []byte("Nothing related here"),
+	}
+	idx, err := BuildRankedIndex(keys, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(label []byte) ([]byte, bool) {
+		data, ok := idx[string(label)]
+		return data, ok
+	}
+
+	results := TopK(lookup, RankedTrapdoor(keys, []byte("Blockchain")), 2)
+	if len(results) != 2 {
+		t.Fatalf("%d results, want 2", len(results))
+	}
+	// e1 scores (1+ln 3)/12, e2 scores 1/6 and e3 scores 1/17
+	if string(results[0].ID) != "e1" || string(results[1].ID) != "e2" {
+		t.Errorf("wrong ranking %s, %s", results[0].ID, results[1].ID)
+	}
+
+	tfs, length := Terms(values["e1"])
+	if DecryptScore(keys, results[0].Score) != RelevanceScore(tfs["blockchain"], length) {
+		t.Errorf("wrong decrypted score")
+	}
+
+	if len(TopK(lookup, RankedTrapdoor(keys, []byte("missing")), 10)) != 0 {
+		t.Errorf("missing term should match nothing")
+	}
+}
@@ -0,0 +1,102 @@
+// An encrypted key-value pair is persisted as a protobuf enkeyvalue record, so that any storage
+// backend and tool can exchange them. The record describes its own format version and cipher suite,
+// and carries the author and key epoch which are needed to pick the keys for decryption.
+
+package client
+
+import (
+	"errors"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// RecordVersion is the format version of enkeyvalue record
+	RecordVersion uint32 = 1
+
+	// SuiteCBC is the legacy AES-256-CBC without authentication
+	SuiteCBC uint32 = 1
+
+	// SuiteGCM is AES-256-GCM with associated data generated by crypto.EncryptAEAD
+	SuiteGCM uint32 = 2
+)
+
+var ErrBadRecord = errors.New("invalid enkeyvalue record")
+
+// suiteOf returns the cipher suite of the ciphertexts
+func suiteOf(ekv *EnKeyValue) (uint32, error) {
+	gcmk, gcmv := crypto.IsVersioned(ekv.EKey), crypto.IsVersioned(ekv.EValue)
+	if gcmk != gcmv {
+		return 0, ErrBadRecord
+	}
+	if gcmk {
+		return SuiteGCM, nil
+	}
+	return SuiteCBC, nil
+}
+
+// MarshalKeyValue serializes the encrypted key-value pair written by the author in the key epoch
+func MarshalKeyValue(ekv *EnKeyValue, pub []byte, epoch uint64) ([]byte, error) {
+	suite, err := suiteOf(ekv)
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &protobuf.Enkeyvalue{
+		Version: proto.Uint32(RecordVersion),
+		Suite:   proto.Uint32(suite),
+		Pub:     pub,
+		Ssek:    ekv.SSEKey,
+		Ssep:    ekv.SSEPrefix,
+		Ssev:    ekv.SSEValue,
+		Ssef:    ekv.SSEFuzzy,
+		Ssem:    ekv.SSEMulti,
+		Ore:     ekv.ORE,
+		He:      ekv.HE,
+		Epath:   ekv.EPath,
+		Ssec:    ekv.SSEConj,
+		Ekey:    ekv.EKey,
+		Evalue:  ekv.EValue,
+	}
+	if epoch != 0 {
+		rec.Epoch = proto.Uint64(epoch)
+	}
+	return proto.Marshal(rec)
+}
+
+// UnmarshalKeyValue parses the record, and returns the encrypted key-value pair
+// along with its author and key epoch. A record without epoch is of epoch 0
+func UnmarshalKeyValue(buf []byte) (ekv *EnKeyValue, pub []byte, epoch uint64, err error) {
+	rec := &protobuf.Enkeyvalue{}
+	err = proto.Unmarshal(buf, rec)
+	if err != nil {
+		return nil, nil, 0, ErrBadRecord
+	}
+
+	if rec.GetVersion() != RecordVersion {
+		return nil, nil, 0, errors.New("UnmarshalKeyValue: record version not supported")
+	}
+
+	ekv = &EnKeyValue{
+		SSEKey:    rec.Ssek,
+		SSEPrefix: rec.Ssep,
+		SSEValue:  rec.Ssev,
+		SSEFuzzy:  rec.Ssef,
+		SSEMulti:  rec.Ssem,
+		ORE:       rec.Ore,
+		HE:        rec.He,
+		EPath:     rec.Epath,
+		SSEConj:   rec.Ssec,
+		EKey:      rec.Ekey,
+		EValue:    rec.Evalue,
+	}
+
+	// the suite must agree with the ciphertexts, so that a record cannot claim a weaker one
+	suite, err := suiteOf(ekv)
+	if err != nil || suite != rec.GetSuite() {
+		return nil, nil, 0, ErrBadRecord
+	}
+	return ekv, rec.Pub, rec.GetEpoch(), nil
+}
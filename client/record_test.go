@@ -0,0 +1,60 @@
+package client
+
+import (
+	"bytes"
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestKeyValueRecord(t *testing.T) {
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	pub, _ := hex.DecodeString(kdcpub)
+
+	kv := &KeyValue{
+		Key:   []byte("name"),
+		Value: []byte("genaro network"),
+	}
+	ekv, err := EncryptKeyValue(keys, fileid, pub, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf, err := MarshalKeyValue(ekv, pub, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rekv, rpub, epoch, err := UnmarshalKeyValue(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rpub, pub) || epoch != 3 || !bytes.Equal(rekv.SSEKey, ekv.SSEKey) {
+		t.Errorf("record mismatch")
+	}
+
+	pkv, err := DecryptKeyValue(keys, fileid, rpub, rekv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(pkv.Value, kv.Value) {
+		t.Errorf("value mismatch")
+	}
+
+	// a record claiming another suite is rejected
+	rec := &protobuf.Enkeyvalue{}
+	proto.Unmarshal(buf, rec)
+	rec.Suite = proto.Uint32(SuiteCBC)
+	bad, _ := proto.Marshal(rec)
+	if _, _, _, err = UnmarshalKeyValue(bad); err != ErrBadRecord {
+		t.Errorf("wrong suite should fail, got %v", err)
+	}
+}
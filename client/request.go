@@ -1,20 +1,25 @@
-// There are five kinds of user's requests to KDC
+// There are eight kinds of user's requests to KDC
 // RequestA: 0xa1 smart contract creator calls for keys
-// RequestB: 0xb2 smart contract modifier calls for keys
+// RequestB: 0xb2 smart contract modifier calls for keys, or for the path keys of some subtrees
 // RequestC: 0xc3 smart contract creator adds new users into whitelist
 // RequestD: 0xd4 smart contract creator informs KDC that the current contract has been completed
 // RequestE: 0xe5 smart contract creator or superuser calls for all the maintainer's keys of the contract
+// RequestF: 0xf6 smart contract maintainer calls for a signed snapshot of the whitelist
+// RequestG: 0x27 smart contract maintainer calls for the multi-user SSE deltas for a storage node
+// RequestH: 0x38 smart contract creator sets the attribute-based access policy
+// Each request is signed over its fields in order, and the elements of List are length-prefixed
+// by kdc.ListToBytes, so that the boundaries between them cannot be moved
 
 package client
 
 import (
 	"bufio"
-	"bytes"
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
 	"fmt"
 	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
 	"genaro-crypto/protobuf"
 	"github.com/golang/protobuf/proto"
 	"io"
@@ -51,7 +56,7 @@ func (user *GenaroUser) CallRequestA(list [][]byte, path string) ([]byte, error)
 
 	// assemble messages
 	epk := crypto.EciesPubToBytes(&user.Epri.PublicKey, DefaultCurve)
-	lb := bytes.Join(list, []byte(""))
+	lb := kdc.ListToBytes(list)
 
 	msg := make([]byte, 1+len(nonce)+len(sn)+len(epk)+len(lb))
 	copy(msg, ty)
@@ -106,12 +111,49 @@ func (user *GenaroUser) CallRequestB(fileid []byte) ([]byte, error) {
 	return proto.Marshal(req)
 }
 
+// CallRequestBWithPaths returns a buffer of RequestB which calls for the path keys of the
+// prefixes, and a maintainer limited to subtrees may only request the prefixes in its scopes
+func (user *GenaroUser) CallRequestBWithPaths(fileid []byte, prefixes []string) ([]byte, error) {
+	ty := []byte{0xb2}
+
+	var list [][]byte
+	for _, p := range prefixes {
+		list = append(list, []byte(p))
+	}
+
+	// assemble messages
+	epk := crypto.EciesPubToBytes(&user.Epri.PublicKey, DefaultCurve)
+	lb := kdc.ListToBytes(list)
+
+	msg := make([]byte, 1+len(fileid)+len(epk)+len(lb))
+	copy(msg, ty)
+	copy(msg[1:], fileid)
+	copy(msg[1+len(fileid):], epk)
+	copy(msg[1+len(fileid)+len(epk):], lb)
+
+	// sign message
+	sign, err := crypto.SignMessage(msg, user.Spri)
+	if err != nil {
+		return nil, fmt.Errorf("CallRequestBWithPaths: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	req := &protobuf.Request{
+		Type: ty,
+		Norf: fileid,
+		Enpk: epk,
+		List: list,
+		Smsg: sign,
+	}
+	return proto.Marshal(req)
+}
+
 // CallRequestC returns a buffer of RequestC
 func (user *GenaroUser) CallRequestC(fileid []byte, list [][]byte) ([]byte, error) {
 	ty := []byte{0xc3}
 
 	// assemble messages )
-	lb := bytes.Join(list, []byte(""))
+	lb := kdc.ListToBytes(list)
 
 	msg := make([]byte, 1+len(fileid)+len(lb))
 	copy(msg, ty)
@@ -186,6 +228,125 @@ func (user *GenaroUser) CallRequestE(fileid []byte) ([]byte, error) {
 	return proto.Marshal(req)
 }
 
+// CallRequestF returns a buffer of RequestF
+func (user *GenaroUser) CallRequestF(fileid []byte) ([]byte, error) {
+	ty := []byte{0xf6}
+
+	// assemble messages
+	msg := make([]byte, 1+len(fileid))
+	copy(msg, ty)
+	copy(msg[1:], fileid)
+
+	// sign message
+	sign, err := crypto.SignMessage(msg, user.Spri)
+	if err != nil {
+		return nil, fmt.Errorf("CallRequestF: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	req := &protobuf.Request{
+		Type: ty,
+		Norf: fileid,
+		Smsg: sign,
+	}
+	return proto.Marshal(req)
+}
+
+// CallRequestG returns a buffer of RequestG, the deltas are encrypted to the storage node
+func (user *GenaroUser) CallRequestG(fileid, storage []byte) ([]byte, error) {
+	ty := []byte{0x27}
+
+	// assemble messages
+	msg := make([]byte, 1+len(fileid)+len(storage))
+	copy(msg, ty)
+	copy(msg[1:], fileid)
+	copy(msg[1+len(fileid):], storage)
+
+	// sign message
+	sign, err := crypto.SignMessage(msg, user.Spri)
+	if err != nil {
+		return nil, fmt.Errorf("CallRequestG: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	req := &protobuf.Request{
+		Type: ty,
+		Norf: fileid,
+		Enpk: storage,
+		Smsg: sign,
+	}
+	return proto.Marshal(req)
+}
+
+// CallRequestH returns a buffer of RequestH, and an empty policy removes the policy.
+// The response is handled by GetResponseC
+func (user *GenaroUser) CallRequestH(fileid []byte, policy string) ([]byte, error) {
+	ty := []byte{0x38}
+
+	list := [][]byte{[]byte(policy)}
+
+	// assemble messages
+	lb := kdc.ListToBytes(list)
+	msg := make([]byte, 1+len(fileid)+len(lb))
+	copy(msg, ty)
+	copy(msg[1:], fileid)
+	copy(msg[1+len(fileid):], lb)
+
+	// sign message
+	sign, err := crypto.SignMessage(msg, user.Spri)
+	if err != nil {
+		return nil, fmt.Errorf("CallRequestH: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	req := &protobuf.Request{
+		Type: ty,
+		Norf: fileid,
+		List: list,
+		Smsg: sign,
+	}
+	return proto.Marshal(req)
+}
+
+// AttachCredentials attaches the attribute credentials of user to the request buffer, so that
+// kdc evaluates the access policy of the contract. The credentials need no signature of user
+func AttachCredentials(request []byte, creds [][]byte) ([]byte, error) {
+	req := &protobuf.Request{}
+	err := proto.Unmarshal(request, req)
+	if err != nil {
+		return nil, errors.New("AttachCredentials: failed to unmarshal request-buffer")
+	}
+
+	req.Cred = append(req.Cred, creds...)
+	return proto.Marshal(req)
+}
+
+// CallUnsealRequest returns a buffer of unseal request, in which the operator submits
+// a Shamir share of the KEK for the challenge of the admin channel of KDC
+func (user *GenaroUser) CallUnsealRequest(challenge, share []byte) ([]byte, error) {
+	ty := []byte{0x1e}
+	norf := append(append([]byte{}, challenge...), share...)
+
+	// assemble messages
+	msg := make([]byte, 1+len(norf))
+	copy(msg, ty)
+	copy(msg[1:], norf)
+
+	// sign message
+	sign, err := crypto.SignMessage(msg, user.Spri)
+	if err != nil {
+		return nil, fmt.Errorf("CallUnsealRequest: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	req := &protobuf.Request{
+		Type: ty,
+		Norf: norf,
+		Smsg: sign,
+	}
+	return proto.Marshal(req)
+}
+
 // ReCallRequestA is for some special situation that client receives no response from KDC after RequestA
 // Others only need to try request again
 func (user *GenaroUser) ReCallRequestA(list [][]byte, path string) ([]byte, error) {
@@ -199,7 +360,7 @@ func (user *GenaroUser) ReCallRequestA(list [][]byte, path string) ([]byte, erro
 
 	// assemble messages
 	epk := crypto.EciesPubToBytes(&user.Epri.PublicKey, DefaultCurve)
-	lb := bytes.Join(list, []byte(""))
+	lb := kdc.ListToBytes(list)
 
 	msg := make([]byte, 1+len(nonce)+len(sn)+len(epk)+len(lb))
 	copy(msg, ty)
@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
 	"genaro-crypto/protobuf"
 	"github.com/golang/protobuf/proto"
 	"testing"
@@ -92,6 +93,35 @@ func TestCallRequestB(t *testing.T) {
 
 }
 
+func TestRequestListBoundaries(t *testing.T) {
+	user := new(GenaroUser)
+	err := user.LoadAsyKey("./testdata/ecdsaB", "./testdata/eciesB")
+	if err != nil {
+		panic(err)
+	}
+
+	buf, err := user.CallRequestBWithPaths(crypto.SaltGen(), []string{"a.b", "c"})
+	if err != nil {
+		panic(err)
+	}
+	pb := &protobuf.Request{}
+	proto.Unmarshal(buf, pb)
+
+	// the message verified by kdc
+	verify := func(list [][]byte) bool {
+		msg := append(append(append([]byte{}, pb.Type...), pb.Norf...), pb.Enpk...)
+		return crypto.VerifySignature(append(msg, kdc.ListToBytes(list)...), pb.Smsg, &user.Spri.PublicKey)
+	}
+	if !verify(pb.List) {
+		t.Fatalf("failed to verify request")
+	}
+
+	// moving the boundary between prefixes breaks the signature
+	if verify([][]byte{[]byte("a"), []byte(".bc")}) {
+		t.Errorf("request with moved list boundary is verified")
+	}
+}
+
 func TestCallRequestC(t *testing.T) {
 	fileid, err := getFileid()
 	if err != nil {
@@ -261,3 +291,37 @@ func TestPrintRequestBuffer(t *testing.T) {
 	}
 	fmt.Println(hex.EncodeToString(buf))
 }
+
+func TestAttachCredentials(t *testing.T) {
+	user := new(GenaroUser)
+	err := user.LoadAsyKey("./testdata/ecdsaB", "./testdata/eciesB")
+	if err != nil {
+		panic(err)
+	}
+
+	fileid := crypto.SaltGen()
+	buf, err := user.CallRequestB(fileid)
+	if err != nil {
+		panic(err)
+	}
+
+	buf, err = AttachCredentials(buf, [][]byte{[]byte("credential")})
+	if err != nil {
+		panic(err)
+	}
+
+	pb := &protobuf.Request{}
+	err = proto.Unmarshal(buf, pb)
+	if err != nil {
+		panic(err)
+	}
+	if len(pb.Cred) != 1 || !bytes.Equal(pb.Cred[0], []byte("credential")) {
+		t.Errorf("credentials are not attached")
+	}
+
+	// the signature of request is not changed
+	msg := append(append([]byte{0xb2}, fileid...), pb.Enpk...)
+	if !crypto.VerifySignNoPub(msg, pb.Smsg) {
+		t.Errorf("failed to verify request with credentials")
+	}
+}
@@ -0,0 +1,225 @@
+// Searcher runs a key path query over every contract a user can access. For each fileid it takes the
+// sub keys of the authors from a KeySource, caches them, generates the tokens and asks the encrypted
+// store for the matched entries, which are decrypted and grouped by fileid and author pub.
+// A file whose keys are unavailable, or an entry which fails to decrypt, is skipped and reported
+// along with the results, so that one bad record cannot block the search of other contracts.
+// A maintainer only gets its own sub keys by RequestB, while the owner or a superuser gets
+// the sub keys of all the maintainers by RequestE.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"sync"
+)
+
+// KeySource returns the sub keys of the authors whose entries the user may read in the file
+type KeySource interface {
+	Keys(fileid []byte) ([]*kdc.KeyOwner, error)
+}
+
+// EncryptedStore is implemented by the storage of encrypted key-values
+type EncryptedStore interface {
+	// Search returns the entries written by pub in the file which match the token
+	Search(fileid, pub []byte, token *KeyToken) ([]*EnKeyValue, error)
+}
+
+// SearchResult is the decrypted entries of an author in a file
+type SearchResult struct {
+	Fileid, Pub []byte
+	KVs         []*KeyValue
+}
+
+// SearchError is a failure of the search in a file, and Pub is nil if the whole file failed
+type SearchError struct {
+	Fileid, Pub []byte
+	Err         error
+}
+
+func (e *SearchError) Error() string {
+	if e.Pub == nil {
+		return fmt.Sprintf("search in %x failed with error: %s", e.Fileid, e.Err.Error())
+	}
+	return fmt.Sprintf("search of %x in %x failed with error: %s", e.Pub, e.Fileid, e.Err.Error())
+}
+
+// Searcher searches across contracts with cached sub keys
+type Searcher struct {
+	source KeySource
+	store  EncryptedStore
+
+	mu    sync.Mutex
+	cache map[string][]*kdc.KeyOwner
+}
+
+// NewSearcher returns a searcher of the key source and the store
+func NewSearcher(source KeySource, store EncryptedStore) *Searcher {
+	return &Searcher{
+		source: source,
+		store:  store,
+		cache:  make(map[string][]*kdc.KeyOwner),
+	}
+}
+
+// keys returns the cached sub keys of the file, or fetches them from the source
+func (s *Searcher) keys(fileid []byte) ([]*kdc.KeyOwner, error) {
+	file := hex.EncodeToString(fileid)
+
+	s.mu.Lock()
+	kos, ok := s.cache[file]
+	s.mu.Unlock()
+	if ok {
+		return kos, nil
+	}
+
+	kos, err := s.source.Keys(fileid)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.cache[file] = kos
+	s.mu.Unlock()
+	return kos, nil
+}
+
+// Forget drops the cached sub keys of the file, for example after its key epoch changed
+func (s *Searcher) Forget(fileid []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.cache, hex.EncodeToString(fileid))
+}
+
+// Search runs the query, which may be a prefix query, over the files. Authors without any
+// matched entry are left out of the results. The failures are skipped and returned in errs
+func (s *Searcher) Search(query []byte, fileids [][]byte) (results []*SearchResult, errs []*SearchError) {
+	for _, fileid := range fileids {
+		kos, err := s.keys(fileid)
+		if err != nil {
+			errs = append(errs, &SearchError{Fileid: fileid, Err: err})
+			continue
+		}
+
+		for _, ko := range kos {
+			token, err := KeyTrapdoor(ko.SKey, query)
+			if err != nil {
+				errs = append(errs, &SearchError{Fileid: fileid, Pub: ko.Pub, Err: err})
+				continue
+			}
+
+			ekvs, err := s.store.Search(fileid, ko.Pub, token)
+			if err != nil {
+				errs = append(errs, &SearchError{Fileid: fileid, Pub: ko.Pub, Err: err})
+				continue
+			}
+
+			res := &SearchResult{Fileid: fileid, Pub: ko.Pub}
+			for _, ekv := range ekvs {
+				kv, err := DecryptKeyValue(&ko.SubKey, fileid, ko.Pub, ekv)
+				if err != nil {
+					errs = append(errs, &SearchError{Fileid: fileid, Pub: ko.Pub, Err: err})
+					continue
+				}
+				res.KVs = append(res.KVs, kv)
+			}
+			if len(res.KVs) > 0 {
+				results = append(results, res)
+			}
+		}
+	}
+	return
+}
+
+// KDCKeySource fetches the sub keys from KDC through the call, which sends a request
+// and returns the response. With Owner set, it calls for all the keys by RequestE.
+// A maintainer limited to subtrees has no sub keys of the file, and gets ErrScopedKeys
+type KDCKeySource struct {
+	User   *GenaroUser
+	KDCPub *ecdsa.PublicKey
+	Call   func(request []byte) ([]byte, error)
+	Owner  bool
+}
+
+// Keys implements KeySource
+func (ks *KDCKeySource) Keys(fileid []byte) ([]*kdc.KeyOwner, error) {
+	var req []byte
+	var err error
+	if ks.Owner {
+		req, err = ks.User.CallRequestE(fileid)
+	} else {
+		req, err = ks.User.CallRequestB(fileid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rep, err := ks.Call(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if ks.Owner {
+		ans, kos, err := ks.User.GetResponseE(rep, fileid, ks.KDCPub)
+		if err != nil {
+			return nil, err
+		}
+		if ans != nil {
+			return nil, errors.New(string(ans))
+		}
+		return kos, nil
+	}
+
+	ans, keys, err := ks.User.GetResponseB(rep, fileid, ks.KDCPub)
+	if err != nil {
+		return nil, err
+	}
+	if ans != nil {
+		return nil, errors.New(string(ans))
+	}
+	pub := crypto.EcdsaPubToBytes(&ks.User.Spri.PublicKey, crypto.DefaultCurve)
+	return []*kdc.KeyOwner{{Pub: pub, SubKey: *keys}}, nil
+}
+
+// MemoryStore is an in-memory EncryptedStore
+type MemoryStore struct {
+	mu      sync.RWMutex
+	entries map[string][]*EnKeyValue
+}
+
+// NewMemoryStore returns an empty store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string][]*EnKeyValue)}
+}
+
+func storeKey(fileid, pub []byte) string {
+	return hex.EncodeToString(fileid) + ":" + hex.EncodeToString(pub)
+}
+
+// Put stores an entry written by pub in the file
+func (ms *MemoryStore) Put(fileid, pub []byte, ekv *EnKeyValue) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	k := storeKey(fileid, pub)
+	ms.entries[k] = append(ms.entries[k], ekv)
+}
+
+// Search implements EncryptedStore
+func (ms *MemoryStore) Search(fileid, pub []byte, token *KeyToken) ([]*EnKeyValue, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	var out []*EnKeyValue
+	for _, ekv := range ms.entries[storeKey(fileid, pub)] {
+		if token.Match(ekv) {
+			out = append(out, ekv)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"errors"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+type staticKeySource struct {
+	keys  map[string][]*kdc.KeyOwner
+	calls int
+}
+
+func (ks *staticKeySource) Keys(fileid []byte) ([]*kdc.KeyOwner, error) {
+	ks.calls++
+	kos, ok := ks.keys[string(fileid)]
+	if !ok {
+		return nil, errors.New("no such fileid")
+	}
+	return kos, nil
+}
+
+func TestSearcher(t *testing.T) {
+	store := NewMemoryStore()
+	source := &staticKeySource{keys: make(map[string][]*kdc.KeyOwner)}
+
+	files := [][]byte{crypto.SaltGen(), crypto.SaltGen()}
+	pubs := [][]byte{[]byte("author A"), []byte("author B")}
+	for _, fileid := range files {
+		for _, pub := range pubs {
+			ko := &kdc.KeyOwner{
+				Pub: pub,
+				SubKey: kdc.SubKey{
+					EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+					SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+				},
+			}
+			source.keys[string(fileid)] = append(source.keys[string(fileid)], ko)
+
+			for _, key := range []string{"scripts.build", "scripts.dev", "name"} {
+				ekv, err := EncryptKeyValue(&ko.SubKey, fileid, pub, &KeyValue{Key: []byte(key), Value: pub})
+				if err != nil {
+					t.Fatal(err)
+				}
+				store.Put(fileid, pub, ekv)
+			}
+		}
+	}
+
+	s := NewSearcher(source, store)
+	results, errs := s.Search([]byte("scripts.*"), files)
+	if errs != nil {
+		t.Fatal(errs[0])
+	}
+	if len(results) != 4 {
+		t.Fatalf("%d groups, want 4", len(results))
+	}
+	for _, res := range results {
+		if len(res.KVs) != 2 || string(res.KVs[0].Value) != string(res.Pub) {
+			t.Errorf("wrong results of %s", res.Pub)
+		}
+	}
+
+	// keys are cached
+	if _, errs = s.Search([]byte("name"), files); errs != nil {
+		t.Fatal(errs[0])
+	}
+	if source.calls != 2 {
+		t.Errorf("key source is called %d times, want 2", source.calls)
+	}
+	s.Forget(files[0])
+	s.Search([]byte("name"), files)
+	if source.calls != 3 {
+		t.Errorf("key source is called %d times, want 3", source.calls)
+	}
+
+	// a bad record and an unknown file are skipped and reported
+	ko := source.keys[string(files[1])][0]
+	bad, _ := EncryptKeyValue(&ko.SubKey, files[0], ko.Pub, &KeyValue{Key: []byte("scripts.test"), Value: ko.Pub})
+	store.Put(files[1], ko.Pub, bad)
+	unknown := crypto.SaltGen()
+
+	results, errs = s.Search([]byte("scripts.*"), append(files, unknown))
+	if len(results) != 4 {
+		t.Fatalf("%d groups, want 4", len(results))
+	}
+	if len(errs) != 2 {
+		t.Fatalf("%d errors, want 2", len(errs))
+	}
+	if string(errs[0].Fileid) != string(files[1]) || string(errs[0].Pub) != string(ko.Pub) {
+		t.Errorf("wrong error of bad record: %s", errs[0])
+	}
+	if string(errs[1].Fileid) != string(unknown) || errs[1].Pub != nil {
+		t.Errorf("wrong error of unknown file: %s", errs[1])
+	}
+}
@@ -0,0 +1,38 @@
+// Each version of a contract has a secure index of its keys, so that a client holding tokens of many
+// contracts can rule contracts in or out before the detailed search. The document identifier is
+// fileid||version, and the keys are indexed along with their path prefixes.
+
+package client
+
+import (
+	"encoding/binary"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+)
+
+// ContractDocID returns the document identifier of a contract version
+func ContractDocID(fileid []byte, version uint64) []byte {
+	docid := make([]byte, len(fileid)+8)
+	copy(docid, fileid)
+	binary.BigEndian.PutUint64(docid[len(fileid):], version)
+	return docid
+}
+
+// BuildContractIndex builds the secure index of the key-values in a contract version,
+// upper is the upper bound of keywords including the path prefixes
+func BuildContractIndex(keys *kdc.SubKey, fileid []byte, version uint64,
+	kvs []*KeyValue, upper int) (*crypto.SecureIndex, error) {
+
+	var keywords [][]byte
+	for _, kv := range kvs {
+		keywords = append(keywords, kv.Key)
+		keywords = append(keywords, PathPrefixes(kv.Key)...)
+	}
+	return crypto.BuildSecureIndex(keys.SKey, ContractDocID(fileid, version), keywords, upper)
+}
+
+// ContractTrapdoor generates the trapdoor of a key path query for secure indexes, the query
+// may be a prefix query such as "scripts.*"
+func ContractTrapdoor(keys *kdc.SubKey, query []byte) [][]byte {
+	return crypto.BloomTrapdoor(keys.SKey, query)
+}
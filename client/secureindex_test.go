@@ -0,0 +1,60 @@
+package client
+
+import (
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestContractIndex(t *testing.T) {
+	keys := &kdc.SubKey{
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+
+	kvs := []*KeyValue{
+		{Key: []byte("scripts.build"), Value: []byte("webpack")},
+		{Key: []byte("name"), Value: []byte("genaro")},
+	}
+	idx, err := BuildContractIndex(keys, fileid, 2, kvs, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, q := range []string{"scripts.build", "scripts.*", "name"} {
+		if !idx.Contains(ContractTrapdoor(keys, []byte(q))) {
+			t.Errorf("%s should be contained", q)
+		}
+	}
+
+	// the index holds far fewer keywords than its upper bound, so false positives are negligible
+	for _, q := range []string{"version", "scripts.test", "dependencies.*"} {
+		if idx.Contains(ContractTrapdoor(keys, []byte(q))) {
+			t.Errorf("%s should not be contained", q)
+		}
+	}
+
+	// a keyword of another fileid or version is not in the index
+	others := []*KeyValue{{Key: []byte("license"), Value: []byte("MIT")}}
+	for _, other := range []struct {
+		fileid  []byte
+		version uint64
+	}{
+		{crypto.SaltGen(), 2},
+		{fileid, 3},
+	} {
+		oidx, err := BuildContractIndex(keys, other.fileid, other.version, others, 16)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !oidx.Contains(ContractTrapdoor(keys, []byte("license"))) {
+			t.Errorf("license should be contained in its own index")
+		}
+		if idx.Contains(ContractTrapdoor(keys, []byte("license"))) {
+			t.Errorf("keyword of another index should not be contained")
+		}
+		if oidx.Contains(ContractTrapdoor(keys, []byte("name"))) {
+			t.Errorf("keyword of version 2 should not be contained in another index")
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Sharing the sub keys of a writer without new key issuance of KDC. The writer seals its sub keys
+// for its own ecies public key by proxy re-encryption, and the sealed keys are kept by the storage.
+// To share them with another maintainer, the writer issues a re-encryption key to the storage, which
+// turns the sealed keys into ones readable by the maintainer without seeing the sub keys.
+// The storage colluding with the maintainer recovers the private key behind the re-encryption key,
+// so the sub keys are sealed for a delegating key pair derived from Epri rather than Epri itself,
+// which opens the responses of KDC. The delegating key pair is derived per fileid, so that the
+// collusion recovers the sub keys of the shared file only.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+)
+
+var ErrBadSealedKey = errors.New("invalid sealed key")
+
+// SealedKey is the sub keys of a writer sealed by proxy re-encryption. CFrag is set
+// once it has been re-encrypted for a delegatee, and then Capsule is no longer needed
+type SealedKey struct {
+	Capsule, CFrag, Cipher []byte
+}
+
+// delegatingKey returns the key pair of user for proxy re-encryption of the fileid
+func (user *GenaroUser) delegatingKey(fileid []byte) *ecdsa.PrivateKey {
+	return crypto.PREDelegatingKey(user.Epri.ExportECDSA(), fileid)
+}
+
+// DelegatingPub returns the public key which the sub keys of the fileid are sealed for and shared to
+func (user *GenaroUser) DelegatingPub(fileid []byte) []byte {
+	return crypto.EcdsaPubToBytes(&user.delegatingKey(fileid).PublicKey, DefaultCurve)
+}
+
+// SealSubKey seals the sub keys of the fileid for the delegating public key of user
+func (user *GenaroUser) SealSubKey(fileid []byte, keys *kdc.SubKey) (*SealedKey, error) {
+	dk := user.delegatingKey(fileid)
+	owner := crypto.EcdsaPubToBytes(&dk.PublicKey, DefaultCurve)
+
+	m := append(append([]byte{}, keys.EKey...), keys.SKey...)
+	capsule, ct, err := crypto.PREEncrypt(&dk.PublicKey, m, associatedData("share", fileid, owner))
+	if err != nil {
+		return nil, fmt.Errorf("SealSubKey: failed to encrypt sub keys with error: %s", err.Error())
+	}
+	return &SealedKey{Capsule: capsule, Cipher: ct}, nil
+}
+
+// ShareWith returns the re-encryption key of the fileid from user to the delegating public key of
+// delegatee, which is the one of the same fileid
+func (user *GenaroUser) ShareWith(fileid, to []byte) ([]byte, error) {
+	pub := crypto.BytesToEcdsaPub(to, DefaultCurve)
+	if pub == nil || pub.X == nil {
+		return nil, errors.New("ShareWith: invalid public key of delegatee")
+	}
+
+	rekey, err := crypto.PREReKey(user.delegatingKey(fileid), pub)
+	if err != nil {
+		return nil, fmt.Errorf("ShareWith: failed to generate re-encryption key with error: %s", err.Error())
+	}
+	return rekey, nil
+}
+
+// ReEncryptSealed turns the sealed keys into the ones of the delegatee, it runs on storage side
+func ReEncryptSealed(rekey []byte, sk *SealedKey) (*SealedKey, error) {
+	cfrag, err := crypto.PREReEncapsulate(rekey, sk.Capsule)
+	if err != nil {
+		return nil, fmt.Errorf("ReEncryptSealed: %s", err.Error())
+	}
+	return &SealedKey{CFrag: cfrag, Cipher: sk.Cipher}, nil
+}
+
+// OpenSealed recovers the sub keys sealed by the owner, whose delegating public key of the fileid is given.
+// The sealed keys are either of user itself or re-encrypted for user
+func (user *GenaroUser) OpenSealed(fileid, owner []byte, sk *SealedKey) (*kdc.SubKey, error) {
+	ad := associatedData("share", fileid, owner)
+	dk := user.delegatingKey(fileid)
+
+	var m []byte
+	var err error
+	if sk.CFrag != nil {
+		m, err = crypto.PREDecryptFrag(dk, sk.CFrag, sk.Cipher, ad)
+	} else {
+		m, err = crypto.PREDecrypt(dk, sk.Capsule, sk.Cipher, ad)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("OpenSealed: failed to decrypt sub keys with error: %s", err.Error())
+	}
+	if len(m) != crypto.EKeyLen+crypto.SKeyLen {
+		return nil, ErrBadSealedKey
+	}
+
+	return &kdc.SubKey{
+		EKey: m[:crypto.EKeyLen],
+		SKey: m[crypto.EKeyLen:],
+	}, nil
+}
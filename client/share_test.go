@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bytes"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"testing"
+)
+
+func TestShareSubKey(t *testing.T) {
+	owner := new(GenaroUser)
+	err := owner.LoadAsyKey("./testdata/ecdsaA", "./testdata/eciesA")
+	if err != nil {
+		panic(err)
+	}
+	maintainer := new(GenaroUser)
+	err = maintainer.LoadAsyKey("./testdata/ecdsaB", "./testdata/eciesB")
+	if err != nil {
+		panic(err)
+	}
+
+	keys := &kdc.SubKey{
+		EKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.EKeyLen),
+		SKey: crypto.KeyDerivFunc(crypto.KeyGen(), crypto.SaltGen(), crypto.SKeyLen),
+	}
+	fileid := crypto.SaltGen()
+	opub := owner.DelegatingPub(fileid)
+	mpub := maintainer.DelegatingPub(fileid)
+
+	sealed, err := owner.SealSubKey(fileid, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = maintainer.OpenSealed(fileid, opub, sealed); err == nil {
+		t.Errorf("maintainer should not open the sealed keys of owner")
+	}
+
+	rekey, err := owner.ShareWith(fileid, mpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shared, err := ReEncryptSealed(rekey, sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := maintainer.OpenSealed(fileid, opub, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.EKey, keys.EKey) || !bytes.Equal(got.SKey, keys.SKey) {
+		t.Errorf("wrong shared sub keys")
+	}
+
+	// the shared keys are bound to the fileid
+	if _, err = maintainer.OpenSealed(crypto.SaltGen(), opub, shared); err == nil {
+		t.Errorf("shared keys of another fileid should fail")
+	}
+
+	// the keys are shared to the delegating key rather than the ecies key of maintainer
+	epub := crypto.EciesPubToBytes(&maintainer.Epri.PublicKey, DefaultCurve)
+	if bytes.Equal(epub, mpub) {
+		t.Errorf("delegating key is the ecies key")
+	}
+	rekey, _ = owner.ShareWith(fileid, epub)
+	shared, _ = ReEncryptSealed(rekey, sealed)
+	if _, err = maintainer.OpenSealed(fileid, opub, shared); err == nil {
+		t.Errorf("keys shared to the ecies key should not be opened")
+	}
+
+	// the re-encryption key of a file opens no sealed keys of another file
+	another := crypto.SaltGen()
+	sealed, _ = owner.SealSubKey(another, keys)
+	rekey, _ = owner.ShareWith(fileid, mpub)
+	shared, _ = ReEncryptSealed(rekey, sealed)
+	if _, err = maintainer.OpenSealed(another, owner.DelegatingPub(another), shared); err == nil {
+		t.Errorf("re-encryption key of a file opened another file")
+	}
+	if bytes.Equal(owner.DelegatingPub(another), opub) {
+		t.Errorf("delegating keys of different files are equal")
+	}
+}
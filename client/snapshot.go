@@ -0,0 +1,79 @@
+// A whitelist snapshot is the response of RequestF signed by KDC. It lists the owner and
+// maintainers of a contract along with the key epoch and the version of the whitelist.
+// Storage nodes keep the signed buffer along with the encrypted contract, so that they
+// can check whether a modification is written by a legal user without connecting to KDC.
+// A user admitted by the access policy of the contract is not a member, so the writer attaches
+// the credentials, and they are checked against the policy and issuers in the snapshot.
+
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	ErrBadSnapshot = errors.New("invalid whitelist snapshot")
+	ErrNotWriter   = errors.New("the signer has no permission to modify the contract")
+)
+
+// VerifySnapshot verifies the signed snapshot buffer by the public key of KDC,
+// and returns the snapshot inside
+func VerifySnapshot(buf []byte, pub *ecdsa.PublicKey) (*protobuf.Snapshot, error) {
+	rp := &protobuf.Response{}
+	err := proto.Unmarshal(buf, rp)
+	if err != nil {
+		return nil, ErrBadSnapshot
+	}
+
+	if !bytes.Equal(rp.Type, []byte{0xfa}) {
+		return nil, ErrBadSnapshot
+	}
+
+	msg := make([]byte, 1+len(rp.Cora))
+	copy(msg, rp.Type)
+	copy(msg[1:], rp.Cora)
+
+	// Verify Signature
+	if !crypto.VerifySignature(msg, rp.Smsg, pub) {
+		return nil, ErrBadSnapshot
+	}
+
+	snap := &protobuf.Snapshot{}
+	err = proto.Unmarshal(rp.Cora, snap)
+	if err != nil {
+		return nil, ErrBadSnapshot
+	}
+	return snap, nil
+}
+
+// VerifyWriter checks the signature of a modification against the snapshot,
+// and returns the role of the writer. The credentials are needed only by the users
+// admitted by policy
+func VerifyWriter(snap *protobuf.Snapshot, msg, sign []byte, creds ...[]byte) (role uint32, err error) {
+	if !crypto.VerifySignNoPub(msg, sign) {
+		return 0, ErrNotWriter
+	}
+
+	pub, err := crypto.PubFromSign(msg, sign)
+	if err != nil {
+		return 0, ErrNotWriter
+	}
+
+	for _, m := range snap.Members {
+		if bytes.Equal(m.Pub, pub) {
+			return m.GetRole(), nil
+		}
+	}
+
+	if kdc.EvaluatePolicy(snap.GetPolicy(), snap.Issuers, creds, pub) {
+		return kdc.RolePolicy, nil
+	}
+	return 0, ErrNotWriter
+}
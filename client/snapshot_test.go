@@ -0,0 +1,146 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// signSnapshot plays the role of kdc to build a snapshot response
+func signSnapshot(snap *protobuf.Snapshot, kpri *ecdsa.PrivateKey) []byte {
+	s, _ := proto.Marshal(snap)
+	msg := append([]byte{0xfa}, s...)
+	sign, err := crypto.SignMessage(msg, kpri)
+	if err != nil {
+		panic(err)
+	}
+	buf, _ := proto.Marshal(&protobuf.Response{
+		Type: []byte{0xfa},
+		Cora: s,
+		Smsg: sign,
+	})
+	return buf
+}
+
+func TestVerifySnapshot(t *testing.T) {
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	owner, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	writer, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	stranger, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+
+	fileid, _ := getFileid()
+	snap := &protobuf.Snapshot{
+		Fileid: fileid,
+		Owner:  crypto.EcdsaPubToBytes(&owner.PublicKey, DefaultCurve),
+		Members: []*protobuf.SnapshotMember{
+			{
+				Pub:  crypto.EcdsaPubToBytes(&owner.PublicKey, DefaultCurve),
+				Role: proto.Uint32(kdc.RoleOwner),
+			},
+			{
+				Pub:  crypto.EcdsaPubToBytes(&writer.PublicKey, DefaultCurve),
+				Role: proto.Uint32(kdc.RoleMaintainer),
+			},
+		},
+		Epoch:   proto.Uint64(1),
+		Version: proto.Uint64(2),
+		Stamp:   proto.Int64(0),
+	}
+	buf := signSnapshot(snap, kpri)
+
+	s, err := VerifySnapshot(buf, &kpri.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.GetVersion() != 2 || len(s.Members) != 2 {
+		t.Errorf("snapshot is not the signed one")
+	}
+
+	// the snapshot must be signed by kdc
+	if _, err = VerifySnapshot(buf, &owner.PublicKey); err != ErrBadSnapshot {
+		t.Errorf("snapshot signed by kdc passed the check of another key")
+	}
+
+	modify := []byte("modification of contract")
+	sign, _ := crypto.SignMessage(modify, writer)
+	role, err := VerifyWriter(s, modify, sign)
+	if err != nil || role != kdc.RoleMaintainer {
+		t.Errorf("maintainer was rejected")
+	}
+
+	sign, _ = crypto.SignMessage(modify, stranger)
+	if _, err = VerifyWriter(s, modify, sign); err != ErrNotWriter {
+		t.Errorf("stranger was accepted as a writer")
+	}
+}
+
+func TestVerifyPolicyWriter(t *testing.T) {
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	ipri, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	owner, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	auditor, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	apub := crypto.EcdsaPubToBytes(&auditor.PublicKey, DefaultCurve)
+
+	issuer := kdc.NewLocalSigner(ipri)
+	expiry := time.Now().Add(time.Hour).Unix()
+	cred, err := kdc.IssueCredential(issuer, apub, map[string]string{"department": "audit"}, expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := kdc.IssueCredential(issuer, apub, map[string]string{"department": "sales"}, expiry)
+
+	fileid, _ := getFileid()
+	snap := &protobuf.Snapshot{
+		Fileid: fileid,
+		Owner:  crypto.EcdsaPubToBytes(&owner.PublicKey, DefaultCurve),
+		Members: []*protobuf.SnapshotMember{
+			{
+				Pub:  crypto.EcdsaPubToBytes(&owner.PublicKey, DefaultCurve),
+				Role: proto.Uint32(kdc.RoleOwner),
+			},
+		},
+		Epoch:   proto.Uint64(1),
+		Version: proto.Uint64(3),
+		Stamp:   proto.Int64(0),
+		Policy:  proto.String("department=audit"),
+		Issuers: [][]byte{crypto.EcdsaPubToBytes(&ipri.PublicKey, DefaultCurve)},
+	}
+	s, err := VerifySnapshot(signSnapshot(snap, kpri), &kpri.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modify := []byte("modification of contract")
+	sign, _ := crypto.SignMessage(modify, auditor)
+	role, err := VerifyWriter(s, modify, sign, cred)
+	if err != nil || role != kdc.RolePolicy {
+		t.Errorf("user admitted by policy was rejected")
+	}
+
+	if _, err = VerifyWriter(s, modify, sign); err != ErrNotWriter {
+		t.Errorf("user admitted by policy was accepted without credentials")
+	}
+	if _, err = VerifyWriter(s, modify, sign, other); err != ErrNotWriter {
+		t.Errorf("credential out of policy was accepted")
+	}
+
+	// the credential is bound to the auditor
+	stranger, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	sign, _ = crypto.SignMessage(modify, stranger)
+	if _, err = VerifyWriter(s, modify, sign, cred); err != ErrNotWriter {
+		t.Errorf("stranger was accepted with the credential of another user")
+	}
+
+	// the issuer must be trusted by the snapshot
+	s.Issuers = nil
+	sign, _ = crypto.SignMessage(modify, auditor)
+	if _, err = VerifyWriter(s, modify, sign, cred); err != ErrNotWriter {
+		t.Errorf("credential of untrusted issuer was accepted")
+	}
+}
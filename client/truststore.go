@@ -0,0 +1,259 @@
+// TrustStore keeps the signing keys of KDC trusted by the client. It starts from the root keys
+// which the client trusts out of band, follows the endorsement chains published by KDC when it
+// rotates its key, and drops the revoked keys. A revocation carries the time since which the key
+// is revoked: the endorsements signed by the key before it still stand, so a rotated key can be
+// retired without breaking its successor, while the ones stamped since then are ignored, and the
+// keys endorsed only by them are dropped. A compromised key is revoked since the time it leaked.
+// A key can be revoked by itself, by a key which endorsed it, or by a root key, so a stolen old
+// key cannot revoke its successor or the root. Trust is recomputed whenever an endorsement or a
+// revocation is added, so they can be fed in any order.
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+var (
+	ErrUntrustedKey   = errors.New("the signing key of kdc is not trusted")
+	ErrBadEndorsement = errors.New("invalid endorsement")
+	ErrBadRevocation  = errors.New("invalid revocation")
+	ErrNoAuthority    = errors.New("the signing key has no authority to revoke the key")
+)
+
+// endorsement of a key by the endorsing key at the stamp
+type endorsement struct {
+	endorser string
+	stamp    int64
+}
+
+// TrustStore is a set of trusted signing keys of KDC indexed by key identifier
+type TrustStore struct {
+	mu       sync.RWMutex
+	roots    map[string]bool
+	keys     map[string]*ecdsa.PublicKey
+	endorsed map[string][]endorsement
+	revoked  map[string]int64
+	anchored map[string]bool
+}
+
+// NewTrustStore returns a trust store of the root keys
+func NewTrustStore(roots ...*ecdsa.PublicKey) *TrustStore {
+	ts := &TrustStore{
+		roots:    make(map[string]bool),
+		keys:     make(map[string]*ecdsa.PublicKey),
+		endorsed: make(map[string][]endorsement),
+		revoked:  make(map[string]int64),
+	}
+	for _, pub := range roots {
+		id := hex.EncodeToString(crypto.KeyID(pub))
+		ts.roots[id] = true
+		ts.keys[id] = pub
+	}
+	ts.refresh()
+	return ts
+}
+
+// valid reports whether the endorsement counts, that is the endorser is connected to a root
+// and was not revoked at the stamp. The lock must be held
+func (ts *TrustStore) valid(e endorsement) bool {
+	if !ts.anchored[e.endorser] {
+		return false
+	}
+	since, ok := ts.revoked[e.endorser]
+	return !ok || e.stamp < since
+}
+
+// refresh recomputes the keys connected to a root by valid endorsements, the lock must be held
+func (ts *TrustStore) refresh() {
+	ts.anchored = make(map[string]bool)
+	for id := range ts.roots {
+		ts.anchored[id] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for id, es := range ts.endorsed {
+			if ts.anchored[id] {
+				continue
+			}
+			for _, e := range es {
+				if ts.valid(e) {
+					ts.anchored[id] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// trusted returns the trusted key of kid, the lock must be held
+func (ts *TrustStore) trusted(kid []byte) *ecdsa.PublicKey {
+	id := hex.EncodeToString(kid)
+	if _, ok := ts.revoked[id]; ok || !ts.anchored[id] {
+		return nil
+	}
+	return ts.keys[id]
+}
+
+// Lookup returns the trusted key of the identifier
+func (ts *TrustStore) Lookup(kid []byte) (*ecdsa.PublicKey, error) {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	pub := ts.trusted(kid)
+	if pub == nil {
+		return nil, ErrUntrustedKey
+	}
+	return pub, nil
+}
+
+// AddEndorsement trusts the endorsed key if the endorsing key is trusted at the stamp
+func (ts *TrustStore) AddEndorsement(buf []byte) error {
+	e := &protobuf.Endorsement{}
+	err := proto.Unmarshal(buf, e)
+	if err != nil {
+		return ErrBadEndorsement
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	sid := hex.EncodeToString(e.Kid)
+	en := endorsement{endorser: sid, stamp: e.GetStamp()}
+	if !ts.valid(en) {
+		return ErrUntrustedKey
+	}
+
+	msg := kdc.EndorsementMsg(e.Kid, e.Pub, e.GetStamp())
+	if !crypto.VerifySignature(msg, e.Smsg, ts.keys[sid]) {
+		return ErrBadEndorsement
+	}
+
+	pub := crypto.BytesToEcdsaPub(e.Pub, crypto.DefaultCurve)
+	if pub == nil || pub.X == nil {
+		return ErrBadEndorsement
+	}
+
+	id := hex.EncodeToString(crypto.KeyID(pub))
+	ts.keys[id] = pub
+	ts.endorsed[id] = append(ts.endorsed[id], en)
+	ts.refresh()
+	return nil
+}
+
+// AddEndorsements follows the endorsement chains in any order, and stops at
+// the first endorsement which cannot be connected to a trusted key
+func (ts *TrustStore) AddEndorsements(bufs [][]byte) error {
+	pending := bufs
+	for len(pending) > 0 {
+		var rest [][]byte
+		for _, buf := range pending {
+			err := ts.AddEndorsement(buf)
+			if err == ErrUntrustedKey {
+				rest = append(rest, buf)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// no more endorsement can be connected
+		if len(rest) == len(pending) {
+			return ErrUntrustedKey
+		}
+		pending = rest
+	}
+	return nil
+}
+
+// mayRevoke reports whether the signer has the authority to revoke the key, that is the
+// signer is the key itself, a root key or an endorser of the key. The lock must be held
+func (ts *TrustStore) mayRevoke(signer, kid string) bool {
+	if signer == kid || ts.roots[signer] {
+		return true
+	}
+	for _, e := range ts.endorsed[kid] {
+		if e.endorser == signer {
+			return true
+		}
+	}
+	return false
+}
+
+// Revoke drops the revoked key if the revocation is signed by a trusted key with the authority,
+// and ignores the endorsements signed by the key since the stamp. A revoked key is never trusted again
+func (ts *TrustStore) Revoke(buf []byte) error {
+	r := &protobuf.Revocation{}
+	err := proto.Unmarshal(buf, r)
+	if err != nil {
+		return ErrBadRevocation
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	signer := ts.trusted(r.Signer)
+	if signer == nil {
+		return ErrUntrustedKey
+	}
+
+	id := hex.EncodeToString(r.Kid)
+	if !ts.mayRevoke(hex.EncodeToString(r.Signer), id) {
+		return ErrNoAuthority
+	}
+
+	msg := kdc.RevocationMsg(r.Kid, r.Signer, r.GetStamp())
+	if !crypto.VerifySignature(msg, r.Smsg, signer) {
+		return ErrBadRevocation
+	}
+
+	// the earliest stamp wins
+	if since, ok := ts.revoked[id]; !ok || r.GetStamp() < since {
+		ts.revoked[id] = r.GetStamp()
+	}
+	ts.refresh()
+	return nil
+}
+
+// ResponseKey returns the trusted key which signed the response buffer. A response without
+// key identifier comes from former KDC, and is verified by the only trusted key if there is one
+func (ts *TrustStore) ResponseKey(rep []byte) (*ecdsa.PublicKey, error) {
+	rp := &protobuf.Response{}
+	err := proto.Unmarshal(rep, rp)
+	if err != nil {
+		return nil, errors.New("ResponseKey: failed to unmarshal response-buffer")
+	}
+
+	if rp.Kid != nil {
+		return ts.Lookup(rp.Kid)
+	}
+
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	var only *ecdsa.PublicKey
+	for id, pub := range ts.keys {
+		if _, ok := ts.revoked[id]; ok || !ts.anchored[id] {
+			continue
+		}
+		if only != nil {
+			return nil, ErrUntrustedKey
+		}
+		only = pub
+	}
+	if only == nil {
+		return nil, ErrUntrustedKey
+	}
+	return only, nil
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"genaro-crypto/protobuf"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestTrustStore(t *testing.T) {
+	k0, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	k1, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	k2, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	evil, _ := crypto.GenerateEcdsaPri(rand.Reader, DefaultCurve)
+
+	s0 := kdc.NewLocalSigner(k0)
+	s1 := kdc.NewLocalSigner(k1)
+
+	e01, err := kdc.EndorseKey(s0, &k1.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	e12, err := kdc.EndorseKey(s1, &k2.PublicKey)
+	if err != nil {
+		panic(err)
+	}
+	eevil, _ := kdc.EndorseKey(kdc.NewLocalSigner(evil), &evil.PublicKey)
+
+	ts := NewTrustStore(&k0.PublicKey)
+
+	// endorsements out of order
+	if err = ts.AddEndorsements([][]byte{e12, e01}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.Lookup(crypto.KeyID(&k2.PublicKey)); err != nil {
+		t.Errorf("endorsement chain is not followed")
+	}
+	if err = ts.AddEndorsement(eevil); err != ErrUntrustedKey {
+		t.Errorf("self endorsement is accepted")
+	}
+
+	// a legacy response without key identifier is verified by the only trusted key
+	kpub, _ := hex.DecodeString(kdcpub)
+	rep, _ := hex.DecodeString(responsetbuf["responseReject"])
+	pub, err := NewTrustStore(crypto.BytesToEcdsaPub(kpub, DefaultCurve)).ResponseKey(rep)
+	if err != nil {
+		t.Errorf("legacy response without key identifier is rejected")
+	}
+	user := new(GenaroUser)
+	if _, _, err = user.GetResponseC(rep, pub); err != nil {
+		t.Errorf("failed to verify legacy response: %s", err)
+	}
+
+	// the old key retires itself after rotation, and its successor is still trusted
+	since := time.Now().Unix() + 1
+	if r, _ := kdc.RevokeKey(s1, crypto.KeyID(&k0.PublicKey), since); ts.Revoke(r) != ErrNoAuthority {
+		t.Errorf("successor revoked the key which endorsed it")
+	}
+	r0, err := kdc.RevokeKey(s0, crypto.KeyID(&k0.PublicKey), since)
+	if err != nil {
+		panic(err)
+	}
+	if err = ts.Revoke(r0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.Lookup(crypto.KeyID(&k0.PublicKey)); err != ErrUntrustedKey {
+		t.Errorf("revoked key is trusted")
+	}
+	if _, err = ts.Lookup(crypto.KeyID(&k1.PublicKey)); err != nil {
+		t.Errorf("successor of revoked key is dropped")
+	}
+
+	// revoked key cannot endorse any more
+	e0evil, _ := kdc.EndorseKey(s0, &evil.PublicKey)
+	if err = ts.AddEndorsement(endorseAt(k0, &evil.PublicKey, since)); err != ErrUntrustedKey {
+		t.Errorf("revoked key endorsed a new key")
+	}
+
+	// the stolen root endorses evil before it is revoked since the leak
+	ts = NewTrustStore(&k0.PublicKey)
+	leak := time.Now().Unix() - 60
+	if err = ts.AddEndorsements([][]byte{e01, e12, e0evil}); err != nil {
+		t.Fatal(err)
+	}
+	rleak, _ := kdc.RevokeKey(s0, crypto.KeyID(&k0.PublicKey), leak)
+	if err = ts.Revoke(rleak); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []*ecdsa.PublicKey{&evil.PublicKey, &k1.PublicKey, &k2.PublicKey} {
+		if _, err = ts.Lookup(crypto.KeyID(k)); err != ErrUntrustedKey {
+			t.Errorf("key endorsed since the leak is trusted")
+		}
+	}
+
+	// the endorsement before the leak still stands
+	ts = NewTrustStore(&k0.PublicKey)
+	ts.Revoke(rleak)
+	if err = ts.AddEndorsements([][]byte{endorseAt(k0, &k1.PublicKey, leak-1), e12}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.Lookup(crypto.KeyID(&k2.PublicKey)); err != nil {
+		t.Errorf("key endorsed before the leak is dropped")
+	}
+
+	// only the key itself, its endorser or a root may revoke it
+	r1, _ := kdc.RevokeKey(kdc.NewLocalSigner(k2), crypto.KeyID(&k1.PublicKey), since)
+	if err = ts.Revoke(r1); err != ErrNoAuthority {
+		t.Errorf("key revoked the key which endorsed it")
+	}
+	r2, _ := kdc.RevokeKey(s1, crypto.KeyID(&k2.PublicKey), since)
+	if err = ts.Revoke(r2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ts.Lookup(crypto.KeyID(&k2.PublicKey)); err != ErrUntrustedKey {
+		t.Errorf("key revoked by its endorser is trusted")
+	}
+}
+
+// endorseAt builds an endorsement of the public key by the private key at the stamp
+func endorseAt(pri *ecdsa.PrivateKey, pub *ecdsa.PublicKey, stamp int64) []byte {
+	kid := crypto.KeyID(&pri.PublicKey)
+	pk := crypto.EcdsaPubToBytes(pub, DefaultCurve)
+	sign, err := crypto.SignMessage(kdc.EndorsementMsg(kid, pk, stamp), pri)
+	if err != nil {
+		panic(err)
+	}
+	buf, _ := proto.Marshal(&protobuf.Endorsement{
+		Kid:   kid,
+		Pub:   pk,
+		Stamp: proto.Int64(stamp),
+		Smsg:  sign,
+	})
+	return buf
+}
@@ -0,0 +1,49 @@
+// kekmigrate wraps the plaintext master keys stored by the former versions of kdc
+// with the key-encryption key. It is safe to run it more than once.
+//
+// Usage:
+//
+//	kekmigrate -kek-file /path/to/kek
+//	GENARO_KDC_KEK=<hex> kekmigrate
+package main
+
+import (
+	"flag"
+	"fmt"
+	"genaro-crypto/kdc"
+	"os"
+
+	"gopkg.in/mgo.v2"
+)
+
+func main() {
+	host := flag.String("host", "localhost", "address of MongoDB")
+	file := flag.String("kek-file", "", "file of the hex encoded KEK")
+	env := flag.String("kek-env", "GENARO_KDC_KEK", "environment variable of the hex encoded KEK")
+	flag.Parse()
+
+	var p kdc.KeyProvider = kdc.EnvKEK(*env)
+	if *file != "" {
+		p = kdc.FileKEK(*file)
+	}
+
+	// check the KEK before touching database
+	if _, err := p.KEK(); err != nil {
+		fmt.Fprintln(os.Stderr, "kekmigrate:", err)
+		os.Exit(1)
+	}
+
+	session, err := mgo.Dial(*host)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kekmigrate: failed to connect with", *host)
+		os.Exit(1)
+	}
+	defer session.Close()
+
+	num, err := kdc.MigrateMasterKeys(session.DB(kdc.MskDB), p)
+	fmt.Printf("%d master keys have been wrapped\n", num)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kekmigrate:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+// kekshares generates a new key-encryption key for sealed kdc, and splits it into Shamir
+// shares for operators. It prints the shares along with the SHA3-256 digest of the KEK,
+// which kdc uses to check the combined KEK. The KEK itself is never printed.
+//
+// Usage:
+//
+//	kekshares -n 5 -k 3
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/kdc"
+	"io"
+	"os"
+)
+
+func main() {
+	n := flag.Int("n", 5, "number of shares")
+	k := flag.Int("k", 3, "number of shares needed to unseal")
+	flag.Parse()
+
+	kek := make([]byte, kdc.KEKLen)
+	if _, err := io.ReadFull(rand.Reader, kek); err != nil {
+		fmt.Fprintln(os.Stderr, "kekshares:", err)
+		os.Exit(1)
+	}
+
+	shares, err := crypto.SplitSecret(kek, *n, *k)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kekshares:", err)
+		os.Exit(1)
+	}
+
+	fmt.Println("digest:", hex.EncodeToString(crypto.SHA3_256(kek)))
+	for i, share := range shares {
+		fmt.Printf("share %d: %s\n", i+1, hex.EncodeToString(share))
+	}
+}
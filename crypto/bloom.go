@@ -0,0 +1,105 @@
+// This secure index is Z-IDX from a paper proposed by Goh, a keyed Bloom filter for each document.
+// Citation: E.-J. Goh, "Secure indexes," Cryptology ePrint Archive, Report 2003/216.
+
+// The trapdoor of keyword w is x_i = PRF(k_i, w) for i = 1..r, where the keys k_i are derived from SKey.
+// The codeword of w in document D is y_i = PRF(x_i, id(D)), and each y_i sets a bit of the Bloom filter
+// of D. Since the codewords depend on the document identifier, the same keyword sets different bits in
+// different documents. The filter is blinded by random bits up to an upper bound of keywords, so that it
+// does not reveal the number of keywords. A filter may give false positives but no false negatives,
+// so it rules documents in or out before the detailed search.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/big"
+)
+
+const (
+	// BloomHashes is the number of hash functions, which gives a false
+	// positive rate of about 0.1% with the filter size of BuildSecureIndex
+	BloomHashes = 10
+)
+
+var ErrBloomParams = errors.New("invalid secure index parameters")
+
+// SecureIndex is the blinded Bloom filter of a document
+type SecureIndex struct {
+	DocID []byte
+	Bits  []byte
+	Size  uint32 // number of bits
+}
+
+// BloomTrapdoor generates the trapdoor of the keyword
+func BloomTrapdoor(skey, keyword []byte) [][]byte {
+	td := make([][]byte, BloomHashes)
+	for i := range td {
+		ki := DeriveKey(skey, []byte{'b', 'l', 'o', 'o', 'm', byte(i)}, SKeyLen)
+		td[i] = HMAC(keyword, ki)
+	}
+	return td
+}
+
+// bloomBit returns the bit of the codeword
+func bloomBit(x, docid []byte, size uint32) uint32 {
+	return binary.BigEndian.Uint32(HMAC(docid, x)[:4]) % size
+}
+
+// BuildSecureIndex builds the secure index of the document with the keywords,
+// upper is the upper bound of keywords in a document, which decides the size of filter
+func BuildSecureIndex(skey, docid []byte, keywords [][]byte, upper int) (*SecureIndex, error) {
+	unique := make(map[string]bool)
+	for _, w := range keywords {
+		unique[string(w)] = true
+	}
+	if upper <= 0 || len(unique) > upper || len(docid) == 0 {
+		return nil, ErrBloomParams
+	}
+
+	size := uint32(math.Ceil(float64(upper*BloomHashes) / math.Ln2))
+	idx := &SecureIndex{
+		DocID: docid,
+		Bits:  make([]byte, (size+7)/8),
+		Size:  size,
+	}
+
+	for w := range unique {
+		for _, x := range BloomTrapdoor(skey, []byte(w)) {
+			idx.set(bloomBit(x, docid, size))
+		}
+	}
+
+	// blind the filter by random bits of the missing keywords
+	for i := 0; i < (upper-len(unique))*BloomHashes; i++ {
+		b, err := rand.Int(rand.Reader, big.NewInt(int64(size)))
+		if err != nil {
+			return nil, err
+		}
+		idx.set(uint32(b.Int64()))
+	}
+	return idx, nil
+}
+
+func (idx *SecureIndex) set(bit uint32) {
+	idx.Bits[bit/8] |= 1 << (bit % 8)
+}
+
+func (idx *SecureIndex) get(bit uint32) bool {
+	return idx.Bits[bit/8]&(1<<(bit%8)) != 0
+}
+
+// Contains reports whether the document may contain the keyword of trapdoor, it runs on storage side
+func (idx *SecureIndex) Contains(trapdoor [][]byte) bool {
+	if idx.Size == 0 || uint32(len(idx.Bits)) < (idx.Size+7)/8 || len(trapdoor) != BloomHashes {
+		return false
+	}
+	for _, x := range trapdoor {
+		if !idx.get(bloomBit(x, idx.DocID, idx.Size)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package crypto
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSecureIndex(t *testing.T) {
+	skey := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+
+	var keywords [][]byte
+	for i := 0; i < 50; i++ {
+		keywords = append(keywords, []byte(fmt.Sprintf("key%d", i)))
+	}
+
+	idx, err := BuildSecureIndex(skey, []byte("doc1"), keywords, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, w := range keywords {
+		if !idx.Contains(BloomTrapdoor(skey, w)) {
+			t.Errorf("%s should be contained", w)
+		}
+	}
+
+	fp := 0
+	for i := 0; i < 1000; i++ {
+		if idx.Contains(BloomTrapdoor(skey, []byte(fmt.Sprintf("missing%d", i)))) {
+			fp++
+		}
+	}
+	if fp > 20 {
+		t.Errorf("too many false positives: %d", fp)
+	}
+
+	// the same keywords set other bits in another document
+	other, _ := BuildSecureIndex(skey, []byte("doc2"), keywords[:1], 1)
+	same, _ := BuildSecureIndex(skey, []byte("doc3"), keywords[:1], 1)
+	if string(other.Bits) == string(same.Bits) {
+		t.Errorf("filters of different documents should differ")
+	}
+
+	if _, err = BuildSecureIndex(skey, []byte("doc1"), keywords, 10); err != ErrBloomParams {
+		t.Errorf("too many keywords should fail")
+	}
+}
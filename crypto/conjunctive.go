@@ -0,0 +1,144 @@
+// Conjunctive keyword search over fields of an entry, such as the key path and the value. It follows the
+// DDH-based construction of Golle et al., where a single token tests the conjunction as a whole, on
+// secp256k1 instead of a pairing group.
+// Citation: P. Golle, J. Staddon and B. Waters, "Secure conjunctive keyword search over encrypted data,"
+// ACNS 2004, pp. 31-45.
+
+// Each keyword w in field j is mapped to a scalar V_j = PRF(SKey, j||w), and V_0 is a secret blinding
+// scalar of the SKey. An entry with random a has the tags R = aG, C_0 = aV_0G and C_j = aV_jG.
+// The token of the keywords w'_j in the fields I is (I, t, s) with random t and s = tV_0 + sum V'_j, and
+// the storage checks sR = tC_0 + sum C_j over I, which holds only if every field matches. The storage
+// learns whether each entry matches the whole conjunction, but not which fields of it match, and the
+// tags of equal entries are unlinkable under DDH. The blinding t makes every token a fresh combination,
+// while tokens over overlapping fields, such as {key} and {key, value}, still let the storage combine them
+// into a test on the remaining fields.
+
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+)
+
+// ConjunctiveToken is the token of a conjunction of keywords in the fields
+type ConjunctiveToken struct {
+	Fields []int  // positions of the queried fields in the tags
+	T, S   []byte // blinding and test scalars
+}
+
+var (
+	ErrNoKeyword = errors.New("conjunction needs at least one keyword")
+	ErrBadField  = errors.New("invalid field of conjunctive token")
+)
+
+// conjScalar derives the scalar of the keyword in the field, and the label 0 is the blinding scalar
+func conjScalar(skey []byte, label int, keyword []byte) *big.Int {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(label))
+	mac := HMAC(append(l[:], keyword...), DeriveKey(skey, []byte("conjunctive"), 32))
+
+	n := DefaultCurve.Params().N
+	k := new(big.Int).SetBytes(DeriveKey(mac, []byte("scalar"), 48))
+	k.Mod(k, new(big.Int).Sub(n, big.NewInt(1)))
+	return k.Add(k, big.NewInt(1))
+}
+
+// ConjunctiveTags generates the tags R||C_0||C_1..C_m of an entry with a keyword in each field
+func ConjunctiveTags(skey []byte, keywords ...[]byte) ([]byte, error) {
+	if len(keywords) == 0 {
+		return nil, ErrNoKeyword
+	}
+	a, err := randScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	n := DefaultCurve.Params().N
+	rx, ry := DefaultCurve.ScalarBaseMult(scalarBytes(a))
+	tags := marshalPoint(rx, ry)
+	for j := 0; j <= len(keywords); j++ {
+		var v *big.Int
+		if j == 0 {
+			v = conjScalar(skey, 0, nil)
+		} else {
+			v = conjScalar(skey, j, keywords[j-1])
+		}
+		v.Mul(v, a)
+		v.Mod(v, n)
+		cx, cy := DefaultCurve.ScalarBaseMult(scalarBytes(v))
+		tags = append(tags, marshalPoint(cx, cy)...)
+	}
+	return tags, nil
+}
+
+// ConjunctiveTrapdoor generates the token of the keywords indexed by their fields,
+// which are the positions of keywords given to ConjunctiveTags
+func ConjunctiveTrapdoor(skey []byte, query map[int][]byte) (*ConjunctiveToken, error) {
+	if len(query) == 0 {
+		return nil, ErrNoKeyword
+	}
+	t, err := randScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	// s = tV_0 + sum V'_j
+	n := DefaultCurve.Params().N
+	s := new(big.Int).Mul(t, conjScalar(skey, 0, nil))
+	token := &ConjunctiveToken{T: scalarBytes(t)}
+	for field, w := range query {
+		if field < 0 {
+			return nil, ErrBadField
+		}
+		s.Add(s, conjScalar(skey, field+1, w))
+		token.Fields = append(token.Fields, field)
+	}
+	s.Mod(s, n)
+	token.S = scalarBytes(s)
+	sort.Ints(token.Fields)
+	return token, nil
+}
+
+// MatchAll judges whether the tags of an entry match every keyword of the token, it runs on storage side
+func MatchAll(token *ConjunctiveToken, tags []byte) bool {
+	if token == nil || len(token.Fields) == 0 || len(token.T) != 32 || len(token.S) != 32 {
+		return false
+	}
+	if len(tags)%65 != 0 || len(tags) < 3*65 {
+		return false
+	}
+	point := func(i int) (x, y *big.Int, err error) {
+		return unmarshalPoint(tags[i*65 : (i+1)*65])
+	}
+
+	rx, ry, err := point(0)
+	if err != nil {
+		return false
+	}
+	cx, cy, err := point(1)
+	if err != nil {
+		return false
+	}
+
+	// tC_0 + sum C_j
+	seen := make(map[int]bool, len(token.Fields))
+	x, y := DefaultCurve.ScalarMult(cx, cy, token.T)
+	for _, field := range token.Fields {
+		if field < 0 || field+2 >= len(tags)/65 || seen[field] {
+			return false
+		}
+		seen[field] = true
+
+		cx, cy, err = point(field + 2)
+		if err != nil {
+			return false
+		}
+		x, y = DefaultCurve.Add(x, y, cx, cy)
+	}
+
+	// sR
+	sx, sy := DefaultCurve.ScalarMult(rx, ry, token.S)
+	return sx.Cmp(x) == 0 && sy.Cmp(y) == 0
+}
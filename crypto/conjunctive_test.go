@@ -0,0 +1,87 @@
+package crypto
+
+import "testing"
+
+func TestConjunctiveSearch(t *testing.T) {
+	skey := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+
+	// fields: key path, value
+	entries := [][][]byte{
+		{[]byte("dependencies.vue"), []byte("^2.5.16")},
+		{[]byte("dependencies.vuex"), []byte("^2.5.16")},
+		{[]byte("dependencies.vue"), []byte("^2.4.0")},
+	}
+
+	var tagged [][]byte
+	for _, e := range entries {
+		tags, err := ConjunctiveTags(skey, e...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tagged = append(tagged, tags)
+	}
+
+	count := func(query map[int][]byte) int {
+		token, err := ConjunctiveTrapdoor(skey, query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, tags := range tagged {
+			if MatchAll(token, tags) {
+				n++
+			}
+		}
+		return n
+	}
+
+	if n := count(map[int][]byte{0: []byte("dependencies.vue"), 1: []byte("^2.5.16")}); n != 1 {
+		t.Errorf("conjunction matches %d entries, want 1", n)
+	}
+	if n := count(map[int][]byte{1: []byte("^2.5.16")}); n != 2 {
+		t.Errorf("single keyword matches %d entries, want 2", n)
+	}
+	if n := count(map[int][]byte{0: []byte("dependencies.vuex"), 1: []byte("^2.4.0")}); n != 0 {
+		t.Errorf("conjunction matches %d entries, want 0", n)
+	}
+
+	// keywords are bound to their fields
+	if n := count(map[int][]byte{0: []byte("^2.5.16")}); n != 0 {
+		t.Errorf("keyword of another field matches %d entries", n)
+	}
+
+	// tags of equal entries and tokens of equal queries are randomized
+	again, _ := ConjunctiveTags(skey, entries[0]...)
+	if string(again) == string(tagged[0]) {
+		t.Errorf("tags of equal entries are linkable")
+	}
+	query := map[int][]byte{0: []byte("dependencies.vue")}
+	t1, _ := ConjunctiveTrapdoor(skey, query)
+	t2, _ := ConjunctiveTrapdoor(skey, query)
+	if string(t1.S) == string(t2.S) {
+		t.Errorf("tokens of equal queries are linkable")
+	}
+
+	// token of another key matches nothing
+	other := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+	token, _ := ConjunctiveTrapdoor(other, query)
+	if MatchAll(token, tagged[0]) {
+		t.Errorf("token of another key should match nothing")
+	}
+
+	// malformed tokens match nothing
+	token, _ = ConjunctiveTrapdoor(skey, query)
+	for _, fields := range [][]int{{2}, {-1}, {0, 0}, nil} {
+		token.Fields = fields
+		if MatchAll(token, tagged[0]) {
+			t.Errorf("token of fields %v matches", fields)
+		}
+	}
+
+	if _, err := ConjunctiveTrapdoor(skey, nil); err != ErrNoKeyword {
+		t.Errorf("empty token should fail")
+	}
+	if _, err := ConjunctiveTrapdoor(skey, map[int][]byte{-1: nil}); err != ErrBadField {
+		t.Errorf("negative field should fail")
+	}
+}
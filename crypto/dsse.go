@@ -0,0 +1,158 @@
+// This dynamic SSE scheme is Mitra from a paper proposed by Chamani et al. in CCS 2018.
+// Citation: J. Ghareh Chamani, D. Papadopoulos, C. Papamanthou and R. Jalili, "New constructions for forward
+// and backward private symmetric searchable encryption," Proceedings of the 2018 ACM SIGSAC Conference on
+// Computer and Communications Security, CCS 2018, pp. 1038-1055.
+
+// The client keeps a counter for each keyword. The c-th update of keyword w is stored by the storage as
+// address = PRF(K, w||c||0), value = (op||id) XOR PRF(K, w||c||1), where K is derived from SKey. Since the
+// address of a new update cannot be computed from former search tokens, the scheme is forward private.
+// To search w, the client sends the addresses of all its updates, and removes the deleted identifiers
+// from the returned values, so that the storage never learns which entries have been deleted.
+
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// operations of dynamic SSE update
+const (
+	DSSEAdd    byte = 0x01
+	DSSEDelete byte = 0x02
+)
+
+var ErrBadOperation = errors.New("invalid dynamic SSE operation")
+
+// DSSEClient generates updates and search tokens, and it keeps the counters of keywords.
+// The counters are the state of client, which must be kept along with SKey
+type DSSEClient struct {
+	mu     sync.Mutex
+	key    []byte
+	counts map[string]uint64
+}
+
+// NewDSSEClient returns a client of SKey with the counters saved before, which may be nil
+func NewDSSEClient(skey []byte, counts map[string]uint64) *DSSEClient {
+	c := &DSSEClient{
+		key:    DeriveKey(skey, []byte("dsse"), SKeyLen),
+		counts: make(map[string]uint64),
+	}
+	for w, n := range counts {
+		c.counts[w] = n
+	}
+	return c
+}
+
+// Counts returns a copy of the counters
+func (c *DSSEClient) Counts() map[string]uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make(map[string]uint64, len(c.counts))
+	for w, n := range c.counts {
+		counts[w] = n
+	}
+	return counts
+}
+
+// dssePRF returns PRF(K, w||c||b) of the size
+func (c *DSSEClient) dssePRF(keyword []byte, count uint64, b byte, size int) []byte {
+	msg := make([]byte, len(keyword)+9)
+	copy(msg, keyword)
+	binary.BigEndian.PutUint64(msg[len(keyword):], count)
+	msg[len(msg)-1] = b
+	return DeriveKey(c.key, msg, size)
+}
+
+// Update adds or deletes the identifier of the keyword, and returns the entry to be stored
+func (c *DSSEClient) Update(op byte, keyword, id []byte) (addr, value []byte, err error) {
+	if op != DSSEAdd && op != DSSEDelete {
+		return nil, nil, ErrBadOperation
+	}
+	if len(id) == 0 {
+		return nil, nil, ErrEmptyIdentifier
+	}
+
+	c.mu.Lock()
+	c.counts[string(keyword)]++
+	count := c.counts[string(keyword)]
+	c.mu.Unlock()
+
+	addr = c.dssePRF(keyword, count, 0x00, HMACSize)
+	value = make([]byte, 1+len(id))
+	value[0] = op
+	copy(value[1:], id)
+	xorBytes(value, value, c.dssePRF(keyword, count, 0x01, len(value)))
+	return addr, value, nil
+}
+
+// Trapdoor returns the addresses of all the updates of keyword
+func (c *DSSEClient) Trapdoor(keyword []byte) [][]byte {
+	c.mu.Lock()
+	count := c.counts[string(keyword)]
+	c.mu.Unlock()
+
+	addrs := make([][]byte, 0, count)
+	for i := uint64(1); i <= count; i++ {
+		addrs = append(addrs, c.dssePRF(keyword, i, 0x00, HMACSize))
+	}
+	return addrs
+}
+
+// Resolve decrypts the values returned by the storage for the token from Trapdoor,
+// and returns the identifiers which have been added but not deleted since. It fails if any
+// value is missing, since a lost deletion would bring back a deleted identifier
+func (c *DSSEClient) Resolve(keyword []byte, values [][]byte) ([][]byte, error) {
+	var ids [][]byte
+	seen := make(map[string]bool)
+
+	// later updates override the former ones
+	for i := len(values) - 1; i >= 0; i-- {
+		if len(values[i]) < 2 {
+			return nil, ErrBadOperation
+		}
+		plain := make([]byte, len(values[i]))
+		xorBytes(plain, values[i], c.dssePRF(keyword, uint64(i+1), 0x01, len(plain)))
+
+		id := string(plain[1:])
+		switch plain[0] {
+		case DSSEAdd:
+			if !seen[id] {
+				ids = append(ids, plain[1:])
+			}
+			seen[id] = true
+		case DSSEDelete:
+			seen[id] = true
+		default:
+			return nil, ErrBadOperation
+		}
+	}
+	return ids, nil
+}
+
+// DSSEStore is the dictionary of updates kept by the storage
+type DSSEStore map[string][]byte
+
+// Put stores an update
+func (s DSSEStore) Put(addr, value []byte) {
+	s[string(addr)] = value
+}
+
+// Search returns the values of the addresses in the token, and a missing address
+// is returned as nil so that the values stay in the order of updates
+func (s DSSEStore) Search(token [][]byte) [][]byte {
+	return SearchDSSE(func(addr []byte) []byte {
+		return s[string(addr)]
+	}, token)
+}
+
+// SearchDSSE returns the values of the addresses by the lookup of any storage backend
+func SearchDSSE(lookup func(addr []byte) []byte, token [][]byte) [][]byte {
+	values := make([][]byte, len(token))
+	for i, addr := range token {
+		values[i] = lookup(addr)
+	}
+	return values
+}
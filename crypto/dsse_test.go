@@ -0,0 +1,70 @@
+package crypto
+
+import (
+	"sort"
+	"testing"
+)
+
+func TestDSSE(t *testing.T) {
+	skey := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+	c := NewDSSEClient(skey, nil)
+	store := make(DSSEStore)
+
+	update := func(op byte, w, id string) {
+		addr, value, err := c.Update(op, []byte(w), []byte(id))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Put(addr, value)
+	}
+	search := func(c *DSSEClient, w string) []string {
+		values := store.Search(c.Trapdoor([]byte(w)))
+		ids, err := c.Resolve([]byte(w), values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, id := range ids {
+			out = append(out, string(id))
+		}
+		sort.Strings(out)
+		return out
+	}
+
+	// the token before an update matches nothing of it
+	old := c.Trapdoor([]byte("name"))
+
+	update(DSSEAdd, "name", "doc1")
+	update(DSSEAdd, "name", "doc2")
+	update(DSSEAdd, "version", "doc2")
+	update(DSSEDelete, "name", "doc1")
+	update(DSSEAdd, "name", "doc3")
+
+	if len(old) != 0 {
+		t.Errorf("former token should be empty")
+	}
+
+	got := search(c, "name")
+	if len(got) != 2 || got[0] != "doc2" || got[1] != "doc3" {
+		t.Errorf("wrong result %v", got)
+	}
+
+	// deleted and added again
+	update(DSSEAdd, "name", "doc1")
+	if got = search(c, "name"); len(got) != 3 {
+		t.Errorf("wrong result %v", got)
+	}
+
+	// state can be restored
+	restored := NewDSSEClient(skey, c.Counts())
+	if got = search(restored, "version"); len(got) != 1 || got[0] != "doc2" {
+		t.Errorf("wrong result %v", got)
+	}
+
+	// a missing value is detected
+	values := store.Search(c.Trapdoor([]byte("name")))
+	values[3] = nil
+	if _, err := c.Resolve([]byte("name"), values); err == nil {
+		t.Errorf("missing value should fail")
+	}
+}
@@ -0,0 +1,151 @@
+// This fuzzy keyword search is based on the wildcard-based fuzzy keyword sets from a paper proposed by
+// Li et al. in IEEE INFOCOM 2010.
+// Citation: J. Li, Q. Wang, C. Wang, N. Cao, K. Ren and W. Lou, "Fuzzy keyword search over encrypted data
+// in cloud computing," 2010 Proceedings IEEE INFOCOM, San Diego, CA, 2010, pp. 1-5.
+
+// The fuzzy set of keyword w with distance d contains every pattern derived from w by at most d wildcard
+// operations, where a wildcard either substitutes a character or is inserted before one. For example, the
+// set of "ab" with distance 1 is {ab, *ab, *b, a*b, a*, ab*}. If the edit distance of two keywords is at
+// most d, their fuzzy sets of distance d intersect. Each pattern is stored as a keyed deterministic tag, and
+// a query is the tags of its own fuzzy set, so the storage matches an entry when the two sets intersect.
+// Since a match is only a candidate, the client decrypts the results and filters them by edit distance.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+const (
+	// MaxFuzzyDistance limits the size of fuzzy sets, which grows as O(len^d)
+	MaxFuzzyDistance = 2
+
+	// FuzzyTagLen is the length of a fuzzy tag
+	FuzzyTagLen = 16
+
+	// fuzzyWildcard is the wildcard in patterns, which is not expected in keywords
+	fuzzyWildcard = 0x00
+)
+
+var ErrFuzzyDistance = errors.New("fuzzy distance out of range")
+
+// shuffle permutes the slices uniformly at random
+func shuffle(list [][]byte) error {
+	for i := len(list) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		k := int(j.Int64())
+		list[i], list[k] = list[k], list[i]
+	}
+	return nil
+}
+
+// fuzzySet returns the wildcard-based fuzzy set of the keyword
+func fuzzySet(keyword []byte, d int) [][]byte {
+	set := map[string]bool{string(keyword): true}
+	level := [][]byte{keyword}
+
+	for i := 0; i < d; i++ {
+		var next [][]byte
+		add := func(p []byte) {
+			if !set[string(p)] {
+				set[string(p)] = true
+				next = append(next, p)
+			}
+		}
+
+		for _, w := range level {
+			for j := 0; j <= len(w); j++ {
+				// insert a wildcard before the j-th character
+				ins := make([]byte, 0, len(w)+1)
+				ins = append(append(append(ins, w[:j]...), fuzzyWildcard), w[j:]...)
+				add(ins)
+
+				// substitute the j-th character
+				if j < len(w) && w[j] != fuzzyWildcard {
+					sub := append([]byte{}, w...)
+					sub[j] = fuzzyWildcard
+					add(sub)
+				}
+			}
+		}
+		level = next
+	}
+
+	patterns := make([][]byte, 0, len(set))
+	for p := range set {
+		patterns = append(patterns, []byte(p))
+	}
+	return patterns
+}
+
+// FuzzyKey derives the key of fuzzy tags from the SKey
+func FuzzyKey(skey []byte) []byte {
+	return DeriveKey(skey, []byte("fuzzy"), SKeyLen)
+}
+
+// FuzzyTags generates the tags of the fuzzy set of the keyword in random order. Both the
+// searchable tags of an entry and the search token of a query are generated by it
+func FuzzyTags(skey, keyword []byte, d int) ([][]byte, error) {
+	if d < 0 || d > MaxFuzzyDistance {
+		return nil, ErrFuzzyDistance
+	}
+
+	key := FuzzyKey(skey)
+	set := fuzzySet(keyword, d)
+	tags := make([][]byte, 0, len(set))
+	for _, p := range set {
+		tags = append(tags, HMAC(p, key)[:FuzzyTagLen])
+	}
+	return tags, shuffle(tags)
+}
+
+// MatchFuzzy judges whether the fuzzy token intersects the tags of an entry
+func MatchFuzzy(token, tags [][]byte) bool {
+	set := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		set[string(tag)] = true
+	}
+	for _, t := range token {
+		if set[string(t)] {
+			return true
+		}
+	}
+	return false
+}
+
+// EditDistance returns the Levenshtein distance of two keywords
+func EditDistance(a, b []byte) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
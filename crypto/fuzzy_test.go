@@ -0,0 +1,57 @@
+package crypto
+
+import "testing"
+
+func TestFuzzySet(t *testing.T) {
+	if n := len(fuzzySet([]byte("ab"), 1)); n != 6 {
+		t.Errorf("fuzzy set of ab has %d patterns, want 6", n)
+	}
+}
+
+func TestFuzzySearch(t *testing.T) {
+	skey := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+
+	for _, d := range []int{1, 2} {
+		tags, err := FuzzyTags(skey, []byte("scripts.build"), d)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, q := range []string{"scripts.build", "scripts.buld", "scripst.build", "script.build", "scripts.builds"} {
+			token, err := FuzzyTags(skey, []byte(q), d)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if EditDistance([]byte(q), []byte("scripts.build")) <= d && !MatchFuzzy(token, tags) {
+				t.Errorf("distance %d: %s should match", d, q)
+			}
+		}
+
+		token, _ := FuzzyTags(skey, []byte("devDependencies"), d)
+		if MatchFuzzy(token, tags) {
+			t.Errorf("distance %d: unrelated keyword should not match", d)
+		}
+	}
+
+	if _, err := FuzzyTags(skey, []byte("name"), 3); err != ErrFuzzyDistance {
+		t.Errorf("distance 3 should fail")
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		d    int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"scripts.build", "scripts.buld", 1},
+		{"scripts", "scripst", 2},
+	}
+	for _, c := range cases {
+		if d := EditDistance([]byte(c.a), []byte(c.b)); d != c.d {
+			t.Errorf("distance of %s and %s is %d, want %d", c.a, c.b, d, c.d)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// This indexed SSE scheme is the basic construction (Pi_bas) from a paper proposed by Cash et al. in NDSS 2014,
+// which follows the encrypted inverted index of Curtmola et al. in CCS 2006.
+// Citation: D. Cash, J. Jaeger, S. Jarecki, C. Jutla, H. Krawczyk, M. Rosu and M. Steiner, "Dynamic searchable
+// encryption in very-large databases: data structures and implementation," NDSS 2014.
+
+// For each keyword w, two keys are derived from SKey: K1 = PRF(SKey, 0x01||w) and K2 = PRF(SKey, 0x02||w).
+// The c-th identifier of w is stored in a dictionary as label = PRF(K1, c), data = id XOR PRF(K2, label).
+// The search token of w is (K1, K2), and the storage looks up the labels for c = 0, 1, ... until one is
+// missing, so that the search time only depends on the number of results. The storage learns the number
+// of entries from the index, and the result identifiers along with the repetition of tokens from searches.
+
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// MaxIdentifierLen is the maximal length of identifiers, which is the longest mask of HKDF-SHA256
+const MaxIdentifierLen = 255 * 32
+
+// EncryptedIndex is the dictionary from labels to encrypted identifiers
+type EncryptedIndex map[string][]byte
+
+// IndexToken is the search token of a keyword
+type IndexToken struct {
+	Label, Data []byte
+}
+
+var (
+	ErrEmptyIdentifier = errors.New("identifier of index should not be empty")
+	ErrLongIdentifier  = errors.New("identifier of index is too long")
+)
+
+// IndexTrapdoor generates the search token of the keyword
+func IndexTrapdoor(skey, keyword []byte) *IndexToken {
+	return &IndexToken{
+		Label: HMAC(append([]byte{0x01}, keyword...), skey),
+		Data:  HMAC(append([]byte{0x02}, keyword...), skey),
+	}
+}
+
+// indexLabel returns the label of the c-th identifier
+func indexLabel(token *IndexToken, c uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], c)
+	return HMAC(b[:], token.Label)
+}
+
+// indexMask xors the identifier with the mask of its label
+func indexMask(token *IndexToken, label, id []byte) []byte {
+	out := make([]byte, len(id))
+	xorBytes(out, id, DeriveKey(token.Data, label, len(id)))
+	return out
+}
+
+// BuildIndex encrypts the inverted index which maps each keyword to its identifiers, such as the
+// ids of records in storage. Identifiers of a keyword are stored in the order given
+func BuildIndex(skey []byte, index map[string][][]byte) (EncryptedIndex, error) {
+	idx := make(EncryptedIndex)
+	for w, ids := range index {
+		token := IndexTrapdoor(skey, []byte(w))
+		for c, id := range ids {
+			if len(id) == 0 {
+				return nil, ErrEmptyIdentifier
+			}
+			if len(id) > MaxIdentifierLen {
+				return nil, ErrLongIdentifier
+			}
+			label := indexLabel(token, uint64(c))
+			idx[string(label)] = indexMask(token, label, id)
+		}
+	}
+	return idx, nil
+}
+
+// SearchIndex returns the identifiers matched by the token. lookup returns the data of a label
+// in the dictionary kept by storage, and reports whether it exists. Data longer than any
+// identifier is never written by BuildIndex, and is skipped
+func SearchIndex(lookup func(label []byte) ([]byte, bool), token *IndexToken) [][]byte {
+	var ids [][]byte
+	for c := uint64(0); ; c++ {
+		label := indexLabel(token, c)
+		data, ok := lookup(label)
+		if !ok {
+			return ids
+		}
+		if len(data) > MaxIdentifierLen {
+			continue
+		}
+		ids = append(ids, indexMask(token, label, data))
+	}
+}
+
+// Search returns the identifiers matched by the token
+func (idx EncryptedIndex) Search(token *IndexToken) [][]byte {
+	return SearchIndex(func(label []byte) ([]byte, bool) {
+		data, ok := idx[string(label)]
+		return data, ok
+	}, token)
+}
@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncryptedIndex(t *testing.T) {
+	skey := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+
+	index := map[string][][]byte{
+		"name":    {[]byte("doc1"), []byte("doc2"), []byte("doc3")},
+		"version": {[]byte("doc2")},
+	}
+	idx, err := BuildIndex(skey, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx) != 4 {
+		t.Errorf("index should have 4 entries, got %d", len(idx))
+	}
+
+	for w, want := range index {
+		ids := idx.Search(IndexTrapdoor(skey, []byte(w)))
+		if len(ids) != len(want) {
+			t.Fatalf("%s: %d results, want %d", w, len(ids), len(want))
+		}
+		for i := range ids {
+			if !bytes.Equal(ids[i], want[i]) {
+				t.Errorf("%s: result %d mismatch", w, i)
+			}
+		}
+	}
+
+	if ids := idx.Search(IndexTrapdoor(skey, []byte("license"))); len(ids) != 0 {
+		t.Errorf("missing keyword should match nothing")
+	}
+
+	// token of another key matches nothing
+	other := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+	if ids := idx.Search(IndexTrapdoor(other, []byte("name"))); len(ids) != 0 {
+		t.Errorf("token of another key should match nothing")
+	}
+
+	// identifiers of the maximal length are masked, and longer ones are rejected
+	long := bytes.Repeat([]byte{0x01}, MaxIdentifierLen)
+	idx, err = BuildIndex(skey, map[string][][]byte{"name": {long}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := IndexTrapdoor(skey, []byte("name"))
+	if ids := idx.Search(token); len(ids) != 1 || !bytes.Equal(ids[0], long) {
+		t.Errorf("identifier of the maximal length mismatch")
+	}
+	if _, err = BuildIndex(skey, map[string][][]byte{"name": {append(long, 0x01)}}); err != ErrLongIdentifier {
+		t.Errorf("too long identifier should be rejected")
+	}
+
+	// oversized data from storage is skipped
+	idx[string(indexLabel(token, 0))] = append(long, 0x01)
+	if ids := idx.Search(token); len(ids) != 0 {
+		t.Errorf("oversized data should be skipped")
+	}
+}
@@ -1,12 +1,17 @@
-// We use PBKDF2 as our key derivation functions used in kdc
-// According to NIST 800-63, the length of salt is at least 4-byte
-// and at least 10,000 iterations are needed
+// Key derivation functions used in kdc. The sub keys of a contract are derived from its master
+// key by one of two KDFs, and the Kdf field of the stored master key chooses between them:
+// HKDF-SHA256 (RFC 5869) for new contracts, since stretching a uniformly random master key buys
+// nothing, and PBKDF2 for old contracts whose records have no Kdf. For PBKDF2, according to
+// NIST 800-63, the length of salt is at least 4-byte and at least 10,000 iterations are needed.
+// DeriveKey is the HKDF expand step, which derives the keys of schemes from a sub key
 
 package crypto
 
 import (
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"golang.org/x/crypto/hkdf"
 	"golang.org/x/crypto/pbkdf2"
 	"io"
 )
@@ -45,3 +50,36 @@ func KeyGen() []byte {
 func KeyDerivFunc(msk, salt []byte, len int) []byte {
 	return pbkdf2.Key(msk, salt, Iter, len, sha256.New)
 }
+
+// HKDF derives a key of the size from the secret by HKDF-SHA256, the info separates the domains of keys
+func HKDF(secret, salt, info []byte, size int) []byte {
+	key := make([]byte, size)
+	_, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), key)
+	if err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// DeriveKey derives a sub key of at most 255*32 bytes from a uniformly random key and a label,
+// by the expand step of HKDF-SHA256 with the label as info. The extract step is skipped since the
+// key is already uniformly random. Unlike KeyDerivFunc, it is cheap and only suitable for high-entropy keys
+func DeriveKey(key, label []byte, size int) []byte {
+	if size > 255*sha256.Size {
+		panic("DeriveKey: size exceeds 255*32 bytes")
+	}
+
+	// T(i) = HMAC(key, T(i-1)||label||i), and the vendored hkdf has no separate expand step
+	mac := hmac.New(sha256.New, key)
+	out := make([]byte, 0, size+sha256.Size)
+	var t []byte
+	for i := 1; len(out) < size; i++ {
+		mac.Reset()
+		mac.Write(t)
+		mac.Write(label)
+		mac.Write([]byte{byte(i)})
+		t = mac.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:size]
+}
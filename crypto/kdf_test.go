@@ -1,6 +1,7 @@
 package crypto
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"testing"
@@ -54,3 +55,38 @@ func TestKeyDerivFunce(t *testing.T) {
 		fmt.Println(hex.EncodeToString(sub))
 	}
 }
+
+func TestDeriveKey(t *testing.T) {
+	key := KeyGen()
+
+	k1 := DeriveKey(key, []byte("a"), 48)
+	if len(k1) != 48 {
+		t.Errorf("wrong size %d", len(k1))
+	}
+	if !bytes.Equal(k1[:32], DeriveKey(key, []byte("a"), 32)) {
+		t.Errorf("prefix of longer key should be the same")
+	}
+	if bytes.Equal(k1[:32], DeriveKey(key, []byte("b"), 32)) {
+		t.Errorf("labels should separate keys")
+	}
+
+	// the expand step of test case 1 of RFC 5869
+	prk, _ := hex.DecodeString("077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+	if hex.EncodeToString(DeriveKey(prk, info, 42)) != okm {
+		t.Errorf("DeriveKey mismatches HKDF-Expand")
+	}
+}
+
+func TestHKDF(t *testing.T) {
+	// test case 1 of RFC 5869
+	ikm, _ := hex.DecodeString("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b")
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+	okm := "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"
+
+	if k := hex.EncodeToString(HKDF(ikm, salt, info, 42)); k != okm {
+		t.Errorf("HKDF = %s, want %s", k, okm)
+	}
+}
@@ -0,0 +1,267 @@
+// Key files can be protected by a passphrase. The encrypted key file follows the layout of
+// the Ethereum keystore v3 JSON: the passphrase is stretched by scrypt, the private key is
+// encrypted by AES-128-CTR with the first half of the derived key, and the ciphertext is
+// authenticated by the Keccak-256 MAC of the second half of the derived key and ciphertext.
+// Since our keys are secp256k1, an ecdsa key file can be imported by Ethereum wallets and vice versa.
+
+// The legacy key file is two lines of hex strings: public key and private key.
+// The loaders accept both formats, and a legacy file is loaded without the passphrase.
+
+package crypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// version of keystore
+	KeyStoreVersion = 3
+
+	// StandardScryptN and StandardScryptP are the scrypt parameters of a key file,
+	// which use 256MB memory and take about 1 second on a modern processor
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+
+	// LightScryptN and LightScryptP use 4MB memory and take about 100ms
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+
+	// maxScryptMem and maxScryptWork bound the scrypt parameters of a key file to the cost of
+	// StandardScryptN and StandardScryptP, so that a crafted key file cannot exhaust memory or
+	// stall the loader: 128*n*r is the memory in bytes and n*r*p is the work
+	maxScryptMem  = 128 * StandardScryptN * scryptR
+	maxScryptWork = StandardScryptN * scryptR * StandardScryptP
+	maxDKLen      = 64
+)
+
+var (
+	ErrNeedPassphrase = errors.New("key file is encrypted, passphrase is needed")
+	ErrDecryptKey     = errors.New("could not decrypt key with given passphrase")
+	ErrScryptParams   = errors.New("scrypt parameters of key file out of range")
+)
+
+// encryptedKeyJSON is the keystore v3 layout
+type encryptedKeyJSON struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	Id      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherparamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherparamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// Address returns the Ethereum address of the public key
+func Address(pk []byte) []byte {
+	if len(pk) == 0 {
+		return nil
+	}
+	return SHA3_256(pk[1:])[12:]
+}
+
+// newUUID returns a random (version 4) UUID
+func newUUID() (string, error) {
+	u := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, u); err != nil {
+		return "", err
+	}
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+}
+
+func aesCTRXOR(key, in, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(in))
+	cipher.NewCTR(block, iv).XORKeyStream(out, in)
+	return out, nil
+}
+
+// EncryptKey encrypts the key pairs by passphrase, and returns the keystore JSON
+func (kp *KeyPairs) EncryptKey(passphrase string, scryptN, scryptP int) ([]byte, error) {
+	salt, err := getRandom(32)
+	if err != nil {
+		return nil, err
+	}
+
+	dk, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+
+	iv, err := getIV(aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	ct, err := aesCTRXOR(dk[:16], kp.Sk, iv)
+	if err != nil {
+		return nil, err
+	}
+	mac := SHA3_256(dk[16:32], ct)
+
+	id, err := newUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	ek := encryptedKeyJSON{
+		Address: hex.EncodeToString(Address(kp.Pk)),
+		Crypto: cryptoJSON{
+			Cipher:     "aes-128-ctr",
+			CipherText: hex.EncodeToString(ct),
+			CipherParams: cipherparamsJSON{
+				IV: hex.EncodeToString(iv),
+			},
+			KDF: "scrypt",
+			KDFParams: map[string]interface{}{
+				"n":     scryptN,
+				"r":     scryptR,
+				"p":     scryptP,
+				"dklen": scryptDKLen,
+				"salt":  hex.EncodeToString(salt),
+			},
+			MAC: hex.EncodeToString(mac),
+		},
+		Id:      id,
+		Version: KeyStoreVersion,
+	}
+	return json.Marshal(ek)
+}
+
+// DecryptKey decrypts the keystore JSON by passphrase, and returns the private key
+func DecryptKey(keyjson []byte, passphrase string) (*ecdsa.PrivateKey, error) {
+	ek := new(encryptedKeyJSON)
+	err := json.Unmarshal(keyjson, ek)
+	if err != nil {
+		return nil, err
+	}
+
+	if ek.Version != KeyStoreVersion {
+		return nil, fmt.Errorf("DecryptKey: version not supported: %d", ek.Version)
+	}
+	if ek.Crypto.Cipher != "aes-128-ctr" || ek.Crypto.KDF != "scrypt" {
+		return nil, fmt.Errorf("DecryptKey: %s with %s not supported", ek.Crypto.Cipher, ek.Crypto.KDF)
+	}
+
+	mac, err := hex.DecodeString(ek.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(ek.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := hex.DecodeString(ek.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	params := ek.Crypto.KDFParams
+	salt, err := hex.DecodeString(fmt.Sprint(params["salt"]))
+	if err != nil {
+		return nil, err
+	}
+	n, r, p, dklen := kdfParam(params, "n"), kdfParam(params, "r"), kdfParam(params, "p"), kdfParam(params, "dklen")
+	if err = checkScryptParams(n, r, p, dklen); err != nil {
+		return nil, err
+	}
+
+	dk, err := scrypt.Key([]byte(passphrase), salt, n, r, p, dklen)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(SHA3_256(dk[16:32], ct), mac) {
+		return nil, ErrDecryptKey
+	}
+
+	sk, err := aesCTRXOR(dk[:16], ct, iv)
+	if err != nil {
+		return nil, err
+	}
+	return bytesToEcdsaPri(sk, DefaultCurve)
+}
+
+func kdfParam(params map[string]interface{}, name string) int {
+	v, _ := params[name].(float64)
+	if v < 0 || v > 1<<30 {
+		return -1
+	}
+	return int(v)
+}
+
+// checkScryptParams rejects the scrypt parameters which cost more than the standard ones
+func checkScryptParams(n, r, p, dklen int) error {
+	if n < 2 || n&(n-1) != 0 || r < 1 || p < 1 || dklen < scryptDKLen || dklen > maxDKLen {
+		return ErrScryptParams
+	}
+	if 128*uint64(n)*uint64(r) > maxScryptMem || uint64(n)*uint64(r)*uint64(p) > maxScryptWork {
+		return ErrScryptParams
+	}
+	return nil
+}
+
+// SaveKeyToFileWithPass saves key pairs to file encrypted by passphrase
+func (kp *KeyPairs) SaveKeyToFileWithPass(path, passphrase string) error {
+	keyjson, err := kp.EncryptKey(passphrase, StandardScryptN, StandardScryptP)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, keyjson, 0600)
+}
+
+// isKeyStore reports whether the content of key file is a keystore JSON
+func isKeyStore(buf []byte) bool {
+	return len(bytes.TrimSpace(buf)) > 0 && bytes.TrimSpace(buf)[0] == '{'
+}
+
+// LoadEcdsaKeyFromFileWithPass loads ecdsa key pairs from either encrypted or legacy file
+func LoadEcdsaKeyFromFileWithPass(path, passphrase string) (pri *ecdsa.PrivateKey, err error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !isKeyStore(buf) {
+		return LoadEcdsaKeyFromFile(path)
+	}
+	return DecryptKey(buf, passphrase)
+}
+
+// LoadEciesKeyFromFileWithPass loads ecies key pairs from either encrypted or legacy file
+func LoadEciesKeyFromFileWithPass(path, passphrase string) (pri *ecies.PrivateKey, err error) {
+	spri, err := LoadEcdsaKeyFromFileWithPass(path, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("LoadEciesKeyFromFileWithPass: failed to load key with error: %s", err.Error())
+	}
+	return ecies.ImportECDSA(spri), nil
+}
@@ -0,0 +1,117 @@
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEncryptDecryptKey(t *testing.T) {
+	pri, _ := GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	kp := new(KeyPairs)
+	kp.EcdsaKeyToBytes(pri, DefaultCurve)
+
+	keyjson, err := kp.EncryptKey("genaro", LightScryptN, LightScryptP)
+	if err != nil {
+		panic(err)
+	}
+
+	p, err := DecryptKey(keyjson, "genaro")
+	if err != nil {
+		panic(err)
+	}
+	if !cmpEcdsaPrivate(pri, p) {
+		t.Errorf("decrypted key not equal to the original one")
+	}
+
+	if _, err = DecryptKey(keyjson, "wrong passphrase"); err != ErrDecryptKey {
+		t.Errorf("key decrypted by wrong passphrase")
+	}
+}
+
+// the test vector of Ethereum keystore v3 with passphrase "testpassword"
+var ethkeyjson = `{"address":"008aeeda4d805471df9b2a5b0f38a0c3bcba786b","crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"83dbcc02d8ccb40e466191a123791e0e"},"ciphertext":"d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c","kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"p":8,"r":1,"salt":"ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},"mac":"2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+
+func TestDecryptEthKey(t *testing.T) {
+	pri, err := DecryptKey([]byte(ethkeyjson), "testpassword")
+	if err != nil {
+		panic(err)
+	}
+
+	pk := EcdsaPubToBytes(&pri.PublicKey, DefaultCurve)
+	if hex.EncodeToString(Address(pk)) != "008aeeda4d805471df9b2a5b0f38a0c3bcba786b" {
+		t.Errorf("address of decrypted key mismatched")
+	}
+}
+
+func TestScryptParams(t *testing.T) {
+	for _, c := range []struct {
+		n, r, p, dklen int
+		ok             bool
+	}{
+		{StandardScryptN, scryptR, StandardScryptP, scryptDKLen, true},
+		{LightScryptN, scryptR, LightScryptP, scryptDKLen, true},
+		{StandardScryptN, 1, 8, scryptDKLen, true},
+		{StandardScryptN * 2, scryptR, StandardScryptP, scryptDKLen, false},
+		{StandardScryptN, scryptR * 2, StandardScryptP, scryptDKLen, false},
+		{StandardScryptN, scryptR, 2, scryptDKLen, false},
+		{1 << 40, scryptR, 1, scryptDKLen, false},
+		{LightScryptN + 1, scryptR, 1, scryptDKLen, false},
+		{LightScryptN, 0, 1, scryptDKLen, false},
+		{LightScryptN, scryptR, 0, scryptDKLen, false},
+		{LightScryptN, scryptR, 1, 16, false},
+		{-1, scryptR, 1, scryptDKLen, false},
+	} {
+		if err := checkScryptParams(c.n, c.r, c.p, c.dklen); (err == nil) != c.ok {
+			t.Errorf("scrypt parameters n=%d r=%d p=%d dklen=%d: %v", c.n, c.r, c.p, c.dklen, err)
+		}
+	}
+
+	// a crafted key file is rejected before scrypt runs
+	crafted := strings.Replace(ethkeyjson, `"n":262144`, `"n":1073741824`, 1)
+	if _, err := DecryptKey([]byte(crafted), "testpassword"); err != ErrScryptParams {
+		t.Errorf("crafted key file is not rejected: %v", err)
+	}
+}
+
+func TestLoadKeyWithPass(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "keystore")
+	defer os.RemoveAll(dir)
+
+	pri, _ := GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	kp := new(KeyPairs)
+	kp.EcdsaKeyToBytes(pri, DefaultCurve)
+
+	keyjson, err := kp.EncryptKey("genaro", LightScryptN, LightScryptP)
+	if err != nil {
+		panic(err)
+	}
+	enc := filepath.Join(dir, "encrypted")
+	ioutil.WriteFile(enc, keyjson, 0600)
+
+	legacy := filepath.Join(dir, "legacy")
+	kp.SaveKeyToFile(legacy)
+
+	// legacy loader asks for passphrase
+	if _, err = LoadEcdsaKeyFromFile(enc); err != ErrNeedPassphrase {
+		t.Errorf("legacy loader did not ask for passphrase")
+	}
+
+	for _, path := range []string{enc, legacy} {
+		p, err := LoadEcdsaKeyFromFileWithPass(path, "genaro")
+		if err != nil {
+			panic(err)
+		}
+		if !cmpEcdsaPrivate(pri, p) {
+			t.Errorf("failed to load %s", path)
+		}
+	}
+
+	if _, err = LoadEciesKeyFromFileWithPass(enc, "genaro"); err != nil {
+		t.Errorf("failed to load ecies key")
+	}
+}
@@ -0,0 +1,144 @@
+// Multi-user SSE lets a reader search the entries written by every maintainer of a contract with one
+// token. It follows the delta conversion of multi-key searchable encryption proposed by Popa et al.,
+// without the pairing since the conversion is done by the storage with material from KDC.
+// Citation: R. A. Popa and N. Zeldovich, "Multi-key searchable encryption," Cryptology ePrint Archive,
+// Report 2013/508.
+
+// Each user u has a scalar k_u derived from the SKey, and H maps keywords onto secp256k1.
+// An entry written by a has the tag r||HMAC(H(w)^k_a, r) with random r, and the token of reader r is
+// H(w)^k_r. KDC, knowing both SKeys, supplies the storage the delta k_a/k_r for each writer a, and the
+// storage converts the token to H(w)^k_a which matches the tags of a. The storage learns which entries
+// match a token, and the delta is useless without a token since H(w)^k_r is pseudorandom under DDH.
+
+// Trust assumption: the delta is a plain scalar, not a group element as in Popa et al., since
+// secp256k1 has no pairing. So the storage node holding deltas must not collude with any reader:
+// the reader knows k_r, and k_a = delta*k_r. With k_a they can make tokens and tags of writer a for
+// any keyword without KDC or storage, although k_a is one-way derived from the SKey of a and reveals
+// neither the SKey nor the EKey. KDC therefore only sends deltas encrypted to registered storage nodes.
+
+package crypto
+
+import (
+	"crypto/hmac"
+	"errors"
+	"math/big"
+)
+
+const (
+	// MUTagLen is the length of a multi-user tag: nonce||mac
+	MUTagLen = 32
+
+	muNonceLen = 16
+)
+
+var ErrBadPoint = errors.New("invalid curve point")
+
+// muScalar derives the scalar of user from the SKey
+func muScalar(skey []byte) *big.Int {
+	n := DefaultCurve.Params().N
+	k := new(big.Int).SetBytes(DeriveKey(skey, []byte("musse"), 48))
+	k.Mod(k, new(big.Int).Sub(n, big.NewInt(1)))
+	return k.Add(k, big.NewInt(1))
+}
+
+// hashToCurve maps the keyword onto the curve by try-and-increment
+func hashToCurve(keyword []byte) (x, y *big.Int) {
+	params := DefaultCurve.Params()
+	p := params.P
+
+	// p = 3 mod 4, so the square root of a is a^((p+1)/4)
+	exp := new(big.Int).Add(p, big.NewInt(1))
+	exp.Rsh(exp, 2)
+
+	for i := uint32(0); ; i++ {
+		ctr := []byte{byte(i >> 24), byte(i >> 16), byte(i >> 8), byte(i)}
+		x = new(big.Int).SetBytes(SHA3_256([]byte("musse"), keyword, ctr))
+		if x.Cmp(p) >= 0 {
+			continue
+		}
+
+		// y^2 = x^3 + b
+		rhs := new(big.Int).Exp(x, big.NewInt(3), p)
+		rhs.Add(rhs, params.B)
+		rhs.Mod(rhs, p)
+
+		y = new(big.Int).Exp(rhs, exp, p)
+		if new(big.Int).Exp(y, big.NewInt(2), p).Cmp(rhs) == 0 {
+			return x, y
+		}
+	}
+}
+
+// marshalPoint encodes a point as 0x04||X||Y
+func marshalPoint(x, y *big.Int) []byte {
+	buf := make([]byte, 65)
+	buf[0] = 0x04
+	xb, yb := x.Bytes(), y.Bytes()
+	copy(buf[33-len(xb):33], xb)
+	copy(buf[65-len(yb):], yb)
+	return buf
+}
+
+// unmarshalPoint decodes a point and checks that it is on the curve
+func unmarshalPoint(buf []byte) (x, y *big.Int, err error) {
+	if len(buf) != 65 || buf[0] != 0x04 {
+		return nil, nil, ErrBadPoint
+	}
+	x = new(big.Int).SetBytes(buf[1:33])
+	y = new(big.Int).SetBytes(buf[33:])
+	if !DefaultCurve.IsOnCurve(x, y) {
+		return nil, nil, ErrBadPoint
+	}
+	return x, y, nil
+}
+
+// MUTag generates a multi-user tag of the keyword for the writer of the SKey
+func MUTag(skey, keyword []byte) ([]byte, error) {
+	hx, hy := hashToCurve(keyword)
+	x, y := DefaultCurve.ScalarMult(hx, hy, muScalar(skey).Bytes())
+
+	r, err := getRandom(muNonceLen)
+	if err != nil {
+		return nil, err
+	}
+	return append(r, HMAC(r, marshalPoint(x, y))[:MUTagLen-muNonceLen]...), nil
+}
+
+// MUTrapdoor generates the multi-user token of the keyword for the reader of the SKey
+func MUTrapdoor(skey, keyword []byte) []byte {
+	hx, hy := hashToCurve(keyword)
+	x, y := DefaultCurve.ScalarMult(hx, hy, muScalar(skey).Bytes())
+	return marshalPoint(x, y)
+}
+
+// MUDelta returns the delta which converts the tokens of reader into the ones of writer
+func MUDelta(reader, writer []byte) []byte {
+	n := DefaultCurve.Params().N
+	inv := new(big.Int).ModInverse(muScalar(reader), n)
+	d := inv.Mul(inv, muScalar(writer))
+	d.Mod(d, n)
+
+	buf := make([]byte, 32)
+	db := d.Bytes()
+	copy(buf[32-len(db):], db)
+	return buf
+}
+
+// MUConvert converts the token of reader by the delta, it runs on storage side
+func MUConvert(token, delta []byte) ([]byte, error) {
+	x, y, err := unmarshalPoint(token)
+	if err != nil {
+		return nil, err
+	}
+	cx, cy := DefaultCurve.ScalarMult(x, y, delta)
+	return marshalPoint(cx, cy), nil
+}
+
+// MUMatch judges whether the converted token matches the tag
+func MUMatch(converted, tag []byte) bool {
+	if len(tag) != MUTagLen {
+		return false
+	}
+	mac := HMAC(tag[:muNonceLen], converted)[:MUTagLen-muNonceLen]
+	return hmac.Equal(mac, tag[muNonceLen:])
+}
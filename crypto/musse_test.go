@@ -0,0 +1,49 @@
+package crypto
+
+import "testing"
+
+func TestMultiUserSearch(t *testing.T) {
+	writers := [][]byte{
+		KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen),
+		KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen),
+	}
+	reader := KeyDerivFunc(KeyGen(), SaltGen(), SKeyLen)
+
+	x, y := hashToCurve([]byte("name"))
+	if !DefaultCurve.IsOnCurve(x, y) {
+		t.Fatal("hashed point is not on curve")
+	}
+
+	token := MUTrapdoor(reader, []byte("name"))
+	for i, w := range writers {
+		tag, err := MUTag(w, []byte("name"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		other, err := MUTag(w, []byte("version"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		converted, err := MUConvert(token, MUDelta(reader, w))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !MUMatch(converted, tag) {
+			t.Errorf("writer %d: token should match", i)
+		}
+		if MUMatch(converted, other) {
+			t.Errorf("writer %d: token should not match another keyword", i)
+		}
+
+		// the delta of another writer does not convert the token
+		wrong, _ := MUConvert(token, MUDelta(reader, writers[1-i]))
+		if MUMatch(wrong, tag) {
+			t.Errorf("writer %d: wrong delta should not match", i)
+		}
+	}
+
+	if _, err := MUConvert(token[:64], MUDelta(reader, writers[0])); err != ErrBadPoint {
+		t.Errorf("invalid token should fail")
+	}
+}
@@ -0,0 +1,92 @@
+// Order-preserving encryption of 16-bit integers, which lets the storage rank encrypted scores.
+// Following the lazy sampling of Boldyreva et al., the ciphertext of the domain point is sampled by
+// a binary search over the domain, in which the image of the middle point is drawn by a PRF from the
+// range left for it. Unlike the original scheme, it is drawn uniformly instead of hypergeometrically.
+// Citation: A. Boldyreva, N. Chenette, Y. Lee and A. O'Neill, "Order-preserving symmetric encryption,"
+// EUROCRYPT 2009, pp. 224-241.
+
+// Each plaintext x owns the bucket [y_x, y_(x+1)) of the range. OPEEncrypt returns y_x, while
+// OPEEncryptRandom returns a random point of the bucket as the one-to-many OPE of Wang et al., so that
+// equal scores do not have equal ciphertexts. Both are decrypted by OPEDecrypt.
+// Citation: C. Wang, N. Cao, J. Li, K. Ren and W. Lou, "Secure ranked keyword search over encrypted
+// cloud data," ICDCS 2010, pp. 253-262.
+// OPE reveals the order of plaintexts and roughly their distances, and should only be used for scores.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"math/big"
+)
+
+const (
+	opeDomainBits = 16
+	opeRangeBits  = 48
+)
+
+// opeMiddle returns the image of the middle point of [lo, hi] whose images are ylo and yhi
+func opeMiddle(key []byte, lo, hi, ylo, yhi uint64) (mid, ymid uint64) {
+	mid = lo + (hi-lo)/2
+
+	// leave at least one point for each plaintext on both sides
+	min := ylo + (mid - lo)
+	max := yhi - (hi - mid)
+
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], lo)
+	binary.BigEndian.PutUint64(b[8:], hi)
+	r := binary.BigEndian.Uint64(HMAC(b[:], key)[:8])
+	return mid, min + r%(max-min+1)
+}
+
+// opeBucket returns the bucket [y_x, y_(x+1)) of the plaintext
+func opeBucket(key []byte, x uint64) (ylo, yhi uint64) {
+	lo, hi := uint64(0), uint64(1)<<opeDomainBits
+	ylo, yhi = 0, uint64(1)<<opeRangeBits
+	for hi-lo > 1 {
+		mid, ymid := opeMiddle(key, lo, hi, ylo, yhi)
+		if x < mid {
+			hi, yhi = mid, ymid
+		} else {
+			lo, ylo = mid, ymid
+		}
+	}
+	return ylo, yhi
+}
+
+// OPEKey derives the key of order-preserving encryption from a sub key
+func OPEKey(key []byte) []byte {
+	return DeriveKey(key, []byte("ope"), SKeyLen)
+}
+
+// OPEEncrypt returns the deterministic order-preserving ciphertext of x
+func OPEEncrypt(key []byte, x uint16) uint64 {
+	y, _ := opeBucket(key, uint64(x))
+	return y
+}
+
+// OPEEncryptRandom returns a random order-preserving ciphertext of x
+func OPEEncryptRandom(key []byte, x uint16) (uint64, error) {
+	ylo, yhi := opeBucket(key, uint64(x))
+	r, err := rand.Int(rand.Reader, new(big.Int).SetUint64(yhi-ylo))
+	if err != nil {
+		return 0, err
+	}
+	return ylo + r.Uint64(), nil
+}
+
+// OPEDecrypt returns the plaintext of the ciphertext
+func OPEDecrypt(key []byte, c uint64) uint16 {
+	lo, hi := uint64(0), uint64(1)<<opeDomainBits
+	ylo, yhi := uint64(0), uint64(1)<<opeRangeBits
+	for hi-lo > 1 {
+		mid, ymid := opeMiddle(key, lo, hi, ylo, yhi)
+		if c < ymid {
+			hi, yhi = mid, ymid
+		} else {
+			lo, ylo = mid, ymid
+		}
+	}
+	return uint16(lo)
+}
@@ -0,0 +1,40 @@
+package crypto
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestOPE(t *testing.T) {
+	key := OPEKey(KeyGen())
+
+	xs := []uint16{0, 1, 2, 100, 101, 30000, 65534, 65535}
+	for i := 0; i < 100; i++ {
+		xs = append(xs, uint16(rand.Intn(65536)))
+	}
+
+	for _, x := range xs {
+		c := OPEEncrypt(key, x)
+		if OPEDecrypt(key, c) != x {
+			t.Errorf("failed to decrypt %d", x)
+		}
+
+		rc, err := OPEEncryptRandom(key, x)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if OPEDecrypt(key, rc) != x {
+			t.Errorf("failed to decrypt random ciphertext of %d", x)
+		}
+
+		// order is preserved against the neighbours
+		if x < 65535 {
+			if rc >= OPEEncrypt(key, x+1) {
+				t.Errorf("order of %d and %d is not preserved", x, x+1)
+			}
+		}
+		if x > 0 && OPEEncrypt(key, x-1) >= c {
+			t.Errorf("order of %d and %d is not preserved", x-1, x)
+		}
+	}
+}
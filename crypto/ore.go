@@ -0,0 +1,62 @@
+// This order-revealing encryption is the practical ORE scheme from a paper proposed by Chenette et al.
+// in FSE 2016.
+// Citation: N. Chenette, K. Lewi, S. A. Weis and D. J. Wu, "Practical order-revealing encryption with
+// limited leakage," Fast Software Encryption, FSE 2016, pp. 474-493.
+
+// The ciphertext of a 64-bit integer x is u_1..u_64 with u_i = PRF(k, i||x[1..i-1]) + x_i mod 3, where
+// x_i is the i-th most significant bit. Anyone can compare two ciphertexts: at the first position where
+// they differ, the larger integer has u_i + 1 mod 3. Besides the order, a comparison reveals the first
+// bit in which the two integers differ.
+
+package crypto
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ORELen is the length of an ORE ciphertext, one byte for each bit
+const ORELen = 64
+
+var ErrBadORE = errors.New("invalid ORE ciphertext")
+
+// OREKey derives the key of order-revealing encryption from a sub key
+func OREKey(key []byte) []byte {
+	return DeriveKey(key, []byte("ore"), SKeyLen)
+}
+
+// OREEncrypt returns the order-revealing ciphertext of x
+func OREEncrypt(key []byte, x uint64) []byte {
+	ct := make([]byte, ORELen)
+	msg := make([]byte, 9)
+	for i := 0; i < ORELen; i++ {
+		// i||the bits of x before the i-th one
+		msg[0] = byte(i)
+		prefix := uint64(0)
+		if i > 0 {
+			prefix = x >> uint(ORELen-i)
+		}
+		binary.BigEndian.PutUint64(msg[1:], prefix)
+
+		bit := byte(x>>uint(ORELen-1-i)) & 1
+		ct[i] = byte((binary.BigEndian.Uint64(HMAC(msg, key)[:8])%3 + uint64(bit)) % 3)
+	}
+	return ct
+}
+
+// ORECompare returns -1, 0 or 1 if the plaintext of a is less than, equal to or greater than the one of b
+func ORECompare(a, b []byte) (int, error) {
+	if len(a) != ORELen || len(b) != ORELen {
+		return 0, ErrBadORE
+	}
+	for i := 0; i < ORELen; i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		if a[i] == (b[i]+1)%3 {
+			return 1, nil
+		}
+		return -1, nil
+	}
+	return 0, nil
+}
@@ -0,0 +1,39 @@
+package crypto
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestORE(t *testing.T) {
+	key := OREKey(KeyGen())
+
+	xs := []uint64{0, 1, 2, 1000, 1001, math.MaxUint64 - 1, math.MaxUint64}
+	for i := 0; i < 50; i++ {
+		xs = append(xs, rand.Uint64())
+	}
+
+	for _, x := range xs {
+		for _, y := range xs {
+			want := 0
+			if x < y {
+				want = -1
+			} else if x > y {
+				want = 1
+			}
+
+			got, err := ORECompare(OREEncrypt(key, x), OREEncrypt(key, y))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Errorf("compare %d and %d: got %d, want %d", x, y, got, want)
+			}
+		}
+	}
+
+	if _, err := ORECompare(OREEncrypt(key, 1), nil); err != ErrBadORE {
+		t.Errorf("invalid ciphertext should fail")
+	}
+}
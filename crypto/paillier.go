@@ -0,0 +1,171 @@
+// Paillier cryptosystem, which is additively homomorphic: the product of two ciphertexts is a ciphertext
+// of the sum of their plaintexts, so that storage nodes can aggregate encrypted values with the public key.
+// Citation: P. Paillier, "Public-key cryptosystems based on composite degree residuosity classes,"
+// EUROCRYPT 1999, pp. 223-238.
+
+// We use g = n+1, so that Enc(m) = (1+m*n) * r^n mod n^2 and Dec(c) = L(c^lambda mod n^2) * mu mod n,
+// where L(u) = (u-1)/n and mu = lambda^-1 mod n. The key is generated deterministically from a seed,
+// so that every holder of the seed derives the same key.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+// PaillierBits is the default size of the modulus n
+const PaillierBits = 2048
+
+var (
+	ErrPaillierKey       = errors.New("invalid paillier key")
+	ErrPaillierMessage   = errors.New("paillier plaintext out of range")
+	ErrPaillierCiphertxt = errors.New("invalid paillier ciphertext")
+)
+
+// PaillierPublicKey is the public key for encryption and aggregation
+type PaillierPublicKey struct {
+	N, NSquared *big.Int
+}
+
+// PaillierPrivateKey is the private key for decryption
+type PaillierPrivateKey struct {
+	PaillierPublicKey
+	Lambda, Mu *big.Int
+}
+
+// seededPrime returns the first prime from a candidate drawn by the seed and label
+func seededPrime(seed, label []byte, bits int) *big.Int {
+	buf := DeriveKey(seed, label, (bits+7)/8)
+
+	// set the top two bits so that the product has the full size, and make it odd
+	p := new(big.Int).SetBytes(buf)
+	p.SetBit(p, bits-1, 1)
+	p.SetBit(p, bits-2, 1)
+	p.SetBit(p, 0, 1)
+	for i := bits; i < len(buf)*8; i++ {
+		p.SetBit(p, i, 0)
+	}
+
+	two := big.NewInt(2)
+	for !p.ProbablyPrime(32) {
+		p.Add(p, two)
+	}
+	return p
+}
+
+// GeneratePaillierKey derives the key of the modulus size from the seed
+func GeneratePaillierKey(seed []byte, bits int) (*PaillierPrivateKey, error) {
+	if bits < 256 || bits%2 != 0 {
+		return nil, ErrPaillierKey
+	}
+
+	one := big.NewInt(1)
+	var p, q *big.Int
+	for i := byte(0); ; i++ {
+		p = seededPrime(seed, []byte{'p', i}, bits/2)
+		q = seededPrime(seed, []byte{'q', i}, bits/2)
+		if p.Cmp(q) == 0 {
+			continue
+		}
+
+		// gcd(pq, (p-1)(q-1)) = 1 holds for primes of the same size, but check it anyway
+		n := new(big.Int).Mul(p, q)
+		phi := new(big.Int).Mul(new(big.Int).Sub(p, one), new(big.Int).Sub(q, one))
+		if new(big.Int).GCD(nil, nil, n, phi).Cmp(one) == 0 {
+			break
+		}
+	}
+
+	n := new(big.Int).Mul(p, q)
+	pm, qm := new(big.Int).Sub(p, one), new(big.Int).Sub(q, one)
+	gcd := new(big.Int).GCD(nil, nil, pm, qm)
+	lambda := new(big.Int).Div(new(big.Int).Mul(pm, qm), gcd)
+	mu := new(big.Int).ModInverse(lambda, n)
+	if mu == nil {
+		return nil, ErrPaillierKey
+	}
+
+	return &PaillierPrivateKey{
+		PaillierPublicKey: PaillierPublicKey{
+			N:        n,
+			NSquared: new(big.Int).Mul(n, n),
+		},
+		Lambda: lambda,
+		Mu:     mu,
+	}, nil
+}
+
+// NewPaillierPublicKey returns the public key of the modulus n
+func NewPaillierPublicKey(n []byte) (*PaillierPublicKey, error) {
+	pn := new(big.Int).SetBytes(n)
+	if pn.BitLen() < 256 || pn.Bit(0) == 0 {
+		return nil, ErrPaillierKey
+	}
+	return &PaillierPublicKey{N: pn, NSquared: new(big.Int).Mul(pn, pn)}, nil
+}
+
+// Encrypt returns a randomized ciphertext of m, which should be less than n
+func (pk *PaillierPublicKey) Encrypt(m *big.Int) ([]byte, error) {
+	if m.Sign() < 0 || m.Cmp(pk.N) >= 0 {
+		return nil, ErrPaillierMessage
+	}
+
+	var r *big.Int
+	for {
+		var err error
+		r, err = rand.Int(rand.Reader, pk.N)
+		if err != nil {
+			return nil, err
+		}
+		if r.Sign() > 0 && new(big.Int).GCD(nil, nil, r, pk.N).Cmp(big.NewInt(1)) == 0 {
+			break
+		}
+	}
+
+	// (1+m*n) * r^n mod n^2
+	c := new(big.Int).Mul(m, pk.N)
+	c.Add(c, big.NewInt(1))
+	c.Mul(c, new(big.Int).Exp(r, pk.N, pk.NSquared))
+	c.Mod(c, pk.NSquared)
+	return c.Bytes(), nil
+}
+
+// ciphertext parses the ciphertext and checks its range
+func (pk *PaillierPublicKey) ciphertext(c []byte) (*big.Int, error) {
+	x := new(big.Int).SetBytes(c)
+	if x.Sign() <= 0 || x.Cmp(pk.NSquared) >= 0 {
+		return nil, ErrPaillierCiphertxt
+	}
+	return x, nil
+}
+
+// Add returns the ciphertext of the sum of all the plaintexts
+func (pk *PaillierPublicKey) Add(cts ...[]byte) ([]byte, error) {
+	sum := big.NewInt(1)
+	for _, c := range cts {
+		x, err := pk.ciphertext(c)
+		if err != nil {
+			return nil, err
+		}
+		sum.Mul(sum, x)
+		sum.Mod(sum, pk.NSquared)
+	}
+	return sum.Bytes(), nil
+}
+
+// Decrypt returns the plaintext of the ciphertext
+func (sk *PaillierPrivateKey) Decrypt(c []byte) (*big.Int, error) {
+	x, err := sk.ciphertext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// L(c^lambda mod n^2) * mu mod n
+	u := new(big.Int).Exp(x, sk.Lambda, sk.NSquared)
+	u.Sub(u, big.NewInt(1))
+	u.Div(u, sk.N)
+	u.Mul(u, sk.Mu)
+	return u.Mod(u, sk.N), nil
+}
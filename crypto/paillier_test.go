@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPaillier(t *testing.T) {
+	seed := KeyGen()
+	sk, err := GeneratePaillierKey(seed, 512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sk.N.BitLen() != 512 {
+		t.Errorf("modulus has %d bits", sk.N.BitLen())
+	}
+
+	// the key is derived from the seed
+	sk2, _ := GeneratePaillierKey(seed, 512)
+	if sk.N.Cmp(sk2.N) != 0 {
+		t.Errorf("the same seed should derive the same key")
+	}
+
+	var cts [][]byte
+	total := int64(0)
+	for _, v := range []int64{0, 1, 1000, 123456789} {
+		c, err := sk.Encrypt(big.NewInt(v))
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, err := sk.Decrypt(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m.Int64() != v {
+			t.Errorf("decrypted %s, want %d", m, v)
+		}
+		cts = append(cts, c)
+		total += v
+	}
+
+	sum, err := sk.Add(cts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := sk.Decrypt(sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Int64() != total {
+		t.Errorf("sum is %s, want %d", m, total)
+	}
+
+	if _, err = sk.Encrypt(sk.N); err != ErrPaillierMessage {
+		t.Errorf("plaintext out of range should fail")
+	}
+}
@@ -157,6 +157,19 @@ func EcdsaPubToBytes(pub *ecdsa.PublicKey, curve elliptic.Curve) []byte {
 	return elliptic.Marshal(curve, pub.X, pub.Y)
 }
 
+// KeyIDLen is the length of key identifier
+const KeyIDLen = 8
+
+// KeyID returns the identifier of an ecdsa public key, which is the first
+// 8 bytes of SHA3-256 hash of the public key bytes
+func KeyID(pub *ecdsa.PublicKey) []byte {
+	pk := EcdsaPubToBytes(pub, DefaultCurve)
+	if pk == nil {
+		return nil
+	}
+	return SHA3_256(pk)[:KeyIDLen]
+}
+
 // create a private key with the given D value
 func bytesToEcdsaPri(d []byte, curve elliptic.Curve) (*ecdsa.PrivateKey, error) {
 	pri := new(ecdsa.PrivateKey)
@@ -300,6 +313,14 @@ func LoadEcdsaKeyFromFile(path string) (pri *ecdsa.PrivateKey, err error) {
 	p, _ := r.ReadString('\n')
 	s, _ := r.ReadString('\n')
 
+	// encrypted key file, see keystore.go
+	if isKeyStore([]byte(p)) {
+		return nil, ErrNeedPassphrase
+	}
+	if len(p) == 0 {
+		return nil, errors.New("LoadEcdsaKeyFromFile: empty key file")
+	}
+
 	// ignore '\n'
 	pk, err := hex.DecodeString(p[:len(p)-1])
 	if err != nil {
@@ -0,0 +1,262 @@
+// Proxy re-encryption lets the holder of a private key delegate the decryption of the ciphertexts
+// written for its public key to another public key, through a proxy which never sees the plaintext.
+// It follows the key encapsulation of Umbral on secp256k1, without the threshold splitting of the
+// re-encryption key and the proofs of correct re-encryption.
+// Citation: D. Nuñez, "Umbral: A Threshold Proxy Re-Encryption Scheme," NuCypher, 2018.
+// Citation: M. Blaze, G. Bleumer and M. Strauss, "Divertible protocols and atomic proxy cryptography,"
+// EUROCRYPT 1998, pp. 127-144.
+
+// Encapsulation for A = aG: random r, u, E = rG, V = uG, s = u + r*H(E, V), and the key is derived from
+// (r+u)A, so that the capsule E||V||s can be checked by anyone: sG = V + H(E, V)E. The owner a decrypts
+// by a(E+V). The re-encryption key from a to B = bG is rk = a/d with d = H(X, B, xB) for random x, and
+// the proxy turns the capsule into the capsule fragment rkE||rkV||X, which b decrypts by d(rkE+rkV)
+// with d = H(X, B, bX).
+// The scheme is not collusion-safe: the proxy and the delegatee together recover a = rk*d. So the
+// re-encryption keys are issued from a delegating key pair derived by PREDelegatingKey, which is
+// used for nothing but proxy re-encryption, and never from a key that opens other ciphertexts. The
+// delegating key is derived per fileid as well, so that a recovered one opens no other file.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"math/big"
+)
+
+const (
+	// CapsuleLen is the length of a capsule: E||V||s
+	CapsuleLen = 65 + 65 + 32
+
+	// CFragLen is the length of a capsule fragment: E'||V'||X
+	CFragLen = 65 + 65 + 65
+
+	// ReKeyLen is the length of a re-encryption key: rk||X
+	ReKeyLen = 32 + 65
+)
+
+var (
+	ErrBadCapsule = errors.New("invalid capsule")
+	ErrBadReKey   = errors.New("invalid re-encryption key")
+)
+
+// randScalar returns a random scalar in [1, N-1]
+func randScalar() (*big.Int, error) {
+	n := DefaultCurve.Params().N
+	k, err := rand.Int(rand.Reader, new(big.Int).Sub(n, big.NewInt(1)))
+	if err != nil {
+		return nil, err
+	}
+	return k.Add(k, big.NewInt(1)), nil
+}
+
+// hashToScalar hashes the parts onto a non-zero scalar
+func hashToScalar(parts ...[]byte) *big.Int {
+	n := DefaultCurve.Params().N
+	h := new(big.Int).SetBytes(SHA3_256(append([][]byte{[]byte("pre")}, parts...)...))
+	h.Mod(h, new(big.Int).Sub(n, big.NewInt(1)))
+	return h.Add(h, big.NewInt(1))
+}
+
+// scalarBytes encodes the scalar in 32 bytes
+func scalarBytes(k *big.Int) []byte {
+	buf := make([]byte, 32)
+	kb := k.Bytes()
+	copy(buf[32-len(kb):], kb)
+	return buf
+}
+
+// preKey derives the symmetric key from the shared point, which is extracted first as it is not
+// uniformly random
+func preKey(x, y *big.Int) []byte {
+	return HKDF(marshalPoint(x, y), nil, []byte("pre"), EKeyLen)
+}
+
+// parseCapsule decodes the capsule and checks sG = V + H(E, V)E
+func parseCapsule(capsule []byte) (ex, ey, vx, vy *big.Int, err error) {
+	if len(capsule) != CapsuleLen {
+		return nil, nil, nil, nil, ErrBadCapsule
+	}
+	ex, ey, err = unmarshalPoint(capsule[:65])
+	if err != nil {
+		return nil, nil, nil, nil, ErrBadCapsule
+	}
+	vx, vy, err = unmarshalPoint(capsule[65:130])
+	if err != nil {
+		return nil, nil, nil, nil, ErrBadCapsule
+	}
+
+	h := hashToScalar(capsule[:130])
+	lx, ly := DefaultCurve.ScalarBaseMult(capsule[130:])
+	hx, hy := DefaultCurve.ScalarMult(ex, ey, scalarBytes(h))
+	rx, ry := DefaultCurve.Add(vx, vy, hx, hy)
+	if lx.Cmp(rx) != 0 || ly.Cmp(ry) != 0 {
+		return nil, nil, nil, nil, ErrBadCapsule
+	}
+	return
+}
+
+// PREEncapsulate generates a symmetric key for the public key along with its capsule
+func PREEncapsulate(pub *ecdsa.PublicKey) (key, capsule []byte, err error) {
+	if pub == nil || pub.X == nil || !DefaultCurve.IsOnCurve(pub.X, pub.Y) {
+		return nil, nil, ErrBadPoint
+	}
+
+	r, err := randScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+	u, err := randScalar()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ex, ey := DefaultCurve.ScalarBaseMult(scalarBytes(r))
+	vx, vy := DefaultCurve.ScalarBaseMult(scalarBytes(u))
+	capsule = append(marshalPoint(ex, ey), marshalPoint(vx, vy)...)
+
+	// s = u + r*H(E, V)
+	n := DefaultCurve.Params().N
+	s := new(big.Int).Mul(r, hashToScalar(capsule))
+	s.Add(s, u)
+	s.Mod(s, n)
+	capsule = append(capsule, scalarBytes(s)...)
+
+	// (r+u)A
+	ru := new(big.Int).Add(r, u)
+	ru.Mod(ru, n)
+	kx, ky := DefaultCurve.ScalarMult(pub.X, pub.Y, scalarBytes(ru))
+	return preKey(kx, ky), capsule, nil
+}
+
+// PREDecapsulate recovers the symmetric key from the capsule by the private key
+func PREDecapsulate(pri *ecdsa.PrivateKey, capsule []byte) ([]byte, error) {
+	ex, ey, vx, vy, err := parseCapsule(capsule)
+	if err != nil {
+		return nil, err
+	}
+
+	// a(E+V)
+	px, py := DefaultCurve.Add(ex, ey, vx, vy)
+	kx, ky := DefaultCurve.ScalarMult(px, py, scalarBytes(pri.D))
+	return preKey(kx, ky), nil
+}
+
+// PREDelegatingKey derives the delegating key pair of the fileid from the private key by a one-way
+// function, so that recovering the delegating key reveals nothing about the private key or the
+// delegating keys of other files
+func PREDelegatingKey(pri *ecdsa.PrivateKey, fileid []byte) *ecdsa.PrivateKey {
+	k := hashToScalar([]byte("delegate"), fileid, scalarBytes(pri.D))
+	dk := &ecdsa.PrivateKey{D: k}
+	dk.Curve = DefaultCurve
+	dk.X, dk.Y = DefaultCurve.ScalarBaseMult(scalarBytes(k))
+	return dk
+}
+
+// PREReKey generates the re-encryption key from the delegating private key to the public key
+func PREReKey(pri *ecdsa.PrivateKey, to *ecdsa.PublicKey) ([]byte, error) {
+	if to == nil || to.X == nil || !DefaultCurve.IsOnCurve(to.X, to.Y) {
+		return nil, ErrBadPoint
+	}
+
+	x, err := randScalar()
+	if err != nil {
+		return nil, err
+	}
+
+	// d = H(X, B, xB)
+	xx, xy := DefaultCurve.ScalarBaseMult(scalarBytes(x))
+	sx, sy := DefaultCurve.ScalarMult(to.X, to.Y, scalarBytes(x))
+	X := marshalPoint(xx, xy)
+	d := hashToScalar(X, marshalPoint(to.X, to.Y), marshalPoint(sx, sy))
+
+	// rk = a/d
+	n := DefaultCurve.Params().N
+	rk := new(big.Int).ModInverse(d, n)
+	rk.Mul(rk, pri.D)
+	rk.Mod(rk, n)
+	return append(scalarBytes(rk), X...), nil
+}
+
+// PREReEncapsulate turns the capsule into a capsule fragment for the delegatee by the
+// re-encryption key, it runs on the proxy which alone learns nothing about the symmetric key
+func PREReEncapsulate(rekey, capsule []byte) ([]byte, error) {
+	if len(rekey) != ReKeyLen {
+		return nil, ErrBadReKey
+	}
+	if _, _, err := unmarshalPoint(rekey[32:]); err != nil {
+		return nil, ErrBadReKey
+	}
+
+	ex, ey, vx, vy, err := parseCapsule(capsule)
+	if err != nil {
+		return nil, err
+	}
+
+	rk := rekey[:32]
+	ex, ey = DefaultCurve.ScalarMult(ex, ey, rk)
+	vx, vy = DefaultCurve.ScalarMult(vx, vy, rk)
+
+	cfrag := append(marshalPoint(ex, ey), marshalPoint(vx, vy)...)
+	return append(cfrag, rekey[32:]...), nil
+}
+
+// PREDecapsulateFrag recovers the symmetric key from the capsule fragment by the private key of delegatee
+func PREDecapsulateFrag(pri *ecdsa.PrivateKey, cfrag []byte) ([]byte, error) {
+	if len(cfrag) != CFragLen {
+		return nil, ErrBadCapsule
+	}
+	ex, ey, err := unmarshalPoint(cfrag[:65])
+	if err != nil {
+		return nil, ErrBadCapsule
+	}
+	vx, vy, err := unmarshalPoint(cfrag[65:130])
+	if err != nil {
+		return nil, ErrBadCapsule
+	}
+	xx, xy, err := unmarshalPoint(cfrag[130:])
+	if err != nil {
+		return nil, ErrBadCapsule
+	}
+
+	// d = H(X, B, bX)
+	sx, sy := DefaultCurve.ScalarMult(xx, xy, scalarBytes(pri.D))
+	d := hashToScalar(cfrag[130:], marshalPoint(pri.X, pri.Y), marshalPoint(sx, sy))
+
+	// d(E'+V')
+	px, py := DefaultCurve.Add(ex, ey, vx, vy)
+	kx, ky := DefaultCurve.ScalarMult(px, py, scalarBytes(d))
+	return preKey(kx, ky), nil
+}
+
+// PREEncrypt encrypts the plaintext for the public key, and returns the capsule along with the ciphertext
+func PREEncrypt(pub *ecdsa.PublicKey, pt, ad []byte) (capsule, ct []byte, err error) {
+	key, capsule, err := PREEncapsulate(pub)
+	if err != nil {
+		return nil, nil, err
+	}
+	ct, err = EncryptAEAD(key, pt, ad)
+	if err != nil {
+		return nil, nil, err
+	}
+	return capsule, ct, nil
+}
+
+// PREDecrypt decrypts the ciphertext with its capsule by the private key
+func PREDecrypt(pri *ecdsa.PrivateKey, capsule, ct, ad []byte) ([]byte, error) {
+	key, err := PREDecapsulate(pri, capsule)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptAEAD(key, ct, ad)
+}
+
+// PREDecryptFrag decrypts the ciphertext with the capsule fragment by the private key of delegatee
+func PREDecryptFrag(pri *ecdsa.PrivateKey, cfrag, ct, ad []byte) ([]byte, error) {
+	key, err := PREDecapsulateFrag(pri, cfrag)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptAEAD(key, ct, ad)
+}
@@ -0,0 +1,99 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"math/big"
+	"testing"
+)
+
+func TestProxyReEncryption(t *testing.T) {
+	alice, _ := GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	bob, _ := GenerateEcdsaPri(rand.Reader, DefaultCurve)
+	carol, _ := GenerateEcdsaPri(rand.Reader, DefaultCurve)
+
+	pt := []byte("sub keys of alice")
+	ad := []byte("fileid")
+	capsule, ct, err := PREEncrypt(&alice.PublicKey, pt, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := PREDecrypt(alice, capsule, ct, ad)
+	if err != nil || !bytes.Equal(m, pt) {
+		t.Errorf("alice failed to decrypt: %v", err)
+	}
+	if _, err = PREDecrypt(bob, capsule, ct, ad); err == nil {
+		t.Errorf("bob should not decrypt without re-encryption")
+	}
+
+	rekey, err := PREReKey(alice, &bob.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfrag, err := PREReEncapsulate(rekey, capsule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err = PREDecryptFrag(bob, cfrag, ct, ad)
+	if err != nil || !bytes.Equal(m, pt) {
+		t.Errorf("bob failed to decrypt the re-encrypted ciphertext: %v", err)
+	}
+	if _, err = PREDecryptFrag(carol, cfrag, ct, ad); err == nil {
+		t.Errorf("carol should not decrypt the fragment for bob")
+	}
+
+	// the proxy rejects a tampered capsule
+	capsule[CapsuleLen-1] ^= 0x01
+	if _, err = PREReEncapsulate(rekey, capsule); err != ErrBadCapsule {
+		t.Errorf("tampered capsule should be rejected")
+	}
+}
+
+func TestPRECollusion(t *testing.T) {
+	alice, _ := GenerateEciesPri(rand.Reader, DefaultCurve)
+	bob, _ := GenerateEcdsaPri(rand.Reader, DefaultCurve)
+
+	fileid, other := SaltGen(), SaltGen()
+	dk := PREDelegatingKey(alice.ExportECDSA(), fileid)
+	if dk.D.Cmp(alice.D) == 0 || !DefaultCurve.IsOnCurve(dk.X, dk.Y) {
+		t.Fatalf("wrong delegating key")
+	}
+	if PREDelegatingKey(alice.ExportECDSA(), fileid).D.Cmp(dk.D) != 0 {
+		t.Errorf("delegating key is not deterministic")
+	}
+	odk := PREDelegatingKey(alice.ExportECDSA(), other)
+	if odk.D.Cmp(dk.D) == 0 {
+		t.Errorf("delegating keys of different files are equal")
+	}
+
+	rekey, err := PREReKey(dk, &bob.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the proxy colluding with bob recovers rk*d
+	xx, xy, _ := unmarshalPoint(rekey[32:])
+	sx, sy := DefaultCurve.ScalarMult(xx, xy, scalarBytes(bob.D))
+	d := hashToScalar(rekey[32:], marshalPoint(bob.X, bob.Y), marshalPoint(sx, sy))
+	k := new(big.Int).SetBytes(rekey[:32])
+	k.Mul(k, d)
+	k.Mod(k, DefaultCurve.Params().N)
+	if k.Cmp(dk.D) != 0 {
+		t.Fatalf("collusion should recover the delegating key only")
+	}
+
+	// which opens no response of kdc encrypted for alice
+	ct, _ := EciesEncrypt(rand.Reader, &alice.PublicKey, []byte("sub keys from kdc"))
+	recovered, _ := BytesToEciesKey(EcdsaPubToBytes(&dk.PublicKey, DefaultCurve), scalarBytes(k), DefaultCurve)
+	if _, err = EciesDecrypt(ct, recovered); err == nil {
+		t.Errorf("recovered key opened the response for alice")
+	}
+
+	// nor the ciphertexts sealed for the delegating key of another file
+	capsule, ct, _ := PREEncrypt(&odk.PublicKey, []byte("sub keys of another file"), other)
+	if _, err = PREDecrypt(dk, capsule, ct, other); err == nil {
+		t.Errorf("recovered key opened another file")
+	}
+}
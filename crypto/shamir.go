@@ -0,0 +1,131 @@
+// Shamir's secret sharing over GF(2^8). Each byte of the secret is shared by an independent
+// random polynomial of degree threshold-1, and all the polynomials of a share are evaluated
+// at the same non-zero point x. A share is the evaluations followed by the one-byte x.
+// Citation: Adi Shamir, "How to share a secret," Communications of the ACM, 22(11), 1979.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+var (
+	ErrShareParams = errors.New("invalid parameters of secret sharing")
+	ErrBadShares   = errors.New("invalid or inconsistent shares")
+)
+
+// gfMul multiplies two elements in GF(2^8) with the AES polynomial x^8+x^4+x^3+x+1
+func gfMul(a, b byte) byte {
+	var p byte
+	for b > 0 {
+		if b&1 == 1 {
+			p ^= a
+		}
+		carry := a & 0x80
+		a <<= 1
+		if carry != 0 {
+			a ^= 0x1b
+		}
+		b >>= 1
+	}
+	return p
+}
+
+// gfInv returns the multiplicative inverse of a non-zero element, a^254 = a^-1
+func gfInv(a byte) byte {
+	r := a
+	for i := 0; i < 6; i++ {
+		r = gfMul(r, r)
+		r = gfMul(r, a)
+	}
+	return gfMul(r, r)
+}
+
+// SplitSecret splits the secret into n shares, any threshold of which can restore the secret
+func SplitSecret(secret []byte, n, threshold int) ([][]byte, error) {
+	if len(secret) == 0 || threshold < 2 || n < threshold || n > 255 {
+		return nil, ErrShareParams
+	}
+
+	shares := make([][]byte, n)
+	for i := range shares {
+		shares[i] = make([]byte, len(secret)+1)
+		shares[i][len(secret)] = byte(i + 1)
+	}
+
+	coeff := make([]byte, threshold)
+	for j, s := range secret {
+		// random polynomial with the constant term s
+		coeff[0] = s
+		if _, err := io.ReadFull(rand.Reader, coeff[1:]); err != nil {
+			return nil, err
+		}
+
+		for i := range shares {
+			x := byte(i + 1)
+
+			// Horner's method
+			var y byte
+			for k := threshold - 1; k >= 0; k-- {
+				y = gfMul(y, x) ^ coeff[k]
+			}
+			shares[i][j] = y
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares restores the secret by Lagrange interpolation at x = 0. If the number of shares
+// is less than the threshold, the result is a random value rather than the secret
+func CombineShares(shares [][]byte) ([]byte, error) {
+	if len(shares) < 2 {
+		return nil, ErrBadShares
+	}
+
+	size := len(shares[0])
+	if size < 2 {
+		return nil, ErrBadShares
+	}
+
+	xs := make([]byte, len(shares))
+	for i, share := range shares {
+		if len(share) != size {
+			return nil, ErrBadShares
+		}
+		xs[i] = share[size-1]
+		if xs[i] == 0 {
+			return nil, ErrBadShares
+		}
+		for k := 0; k < i; k++ {
+			if xs[k] == xs[i] {
+				return nil, ErrBadShares
+			}
+		}
+	}
+
+	// Lagrange basis at x = 0: l_i = prod x_k / (x_k - x_i), subtraction is xor in GF(2^8)
+	basis := make([]byte, len(shares))
+	for i := range shares {
+		num, den := byte(1), byte(1)
+		for k := range shares {
+			if k == i {
+				continue
+			}
+			num = gfMul(num, xs[k])
+			den = gfMul(den, xs[k]^xs[i])
+		}
+		basis[i] = gfMul(num, gfInv(den))
+	}
+
+	secret := make([]byte, size-1)
+	for j := range secret {
+		var s byte
+		for i, share := range shares {
+			s ^= gfMul(share[j], basis[i])
+		}
+		secret[j] = s
+	}
+	return secret, nil
+}
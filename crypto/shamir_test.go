@@ -0,0 +1,56 @@
+package crypto
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGFInv(t *testing.T) {
+	for a := 1; a < 256; a++ {
+		if gfMul(byte(a), gfInv(byte(a))) != 1 {
+			t.Fatalf("wrong inverse of %d", a)
+		}
+	}
+}
+
+func TestSplitCombine(t *testing.T) {
+	secret := KeyGen()
+
+	shares, err := SplitSecret(secret, 5, 3)
+	if err != nil {
+		panic(err)
+	}
+
+	// any 3 shares restore the secret
+	for _, set := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var sub [][]byte
+		for _, i := range set {
+			sub = append(sub, shares[i])
+		}
+		s, err := CombineShares(sub)
+		if err != nil {
+			panic(err)
+		}
+		if !bytes.Equal(s, secret) {
+			t.Errorf("shares %v failed to restore the secret", set)
+		}
+	}
+
+	// 2 shares are not enough
+	s, err := CombineShares(shares[:2])
+	if err != nil {
+		panic(err)
+	}
+	if bytes.Equal(s, secret) {
+		t.Errorf("secret restored below the threshold")
+	}
+
+	// the same share twice
+	if _, err = CombineShares([][]byte{shares[0], shares[0], shares[1]}); err != ErrBadShares {
+		t.Errorf("duplicated shares are accepted")
+	}
+
+	if _, err = SplitSecret(secret, 2, 3); err != ErrShareParams {
+		t.Errorf("threshold larger than the number of shares is accepted")
+	}
+}
@@ -163,7 +163,7 @@ func Trapdoor(keyword, skey []byte) (token []byte, err error) {
 	return token, nil
 }
 
-//  Matching judges whether the token and the cipher contain same keyword
+// Matching judges whether the token and the cipher contain same keyword
 func Matching(token, scipher []byte) bool {
 	if len(scipher) != SSize {
 		return false
@@ -174,4 +174,20 @@ func Matching(token, scipher []byte) bool {
 
 	rlen := SSize - HMACSize
 	return bytes.Equal(sc[rlen:], HMAC(sc[:rlen], token[SSize:]))
-}
\ No newline at end of file
+}
+
+// ValueKey derives the searchable encryption key of values from the SKey,
+// so that a token of key cannot match values and vice versa
+func ValueKey(skey []byte) []byte {
+	return DeriveKey(skey, []byte("value"), SKeyLen)
+}
+
+// SearchableValueEnc generates a searchable ciphertext for the value
+func SearchableValueEnc(value, skey []byte) (scipher []byte, err error) {
+	return SearchableEnc(value, ValueKey(skey))
+}
+
+// ValueTrapdoor generates a value search token, which matches the ciphertexts of equal values
+func ValueTrapdoor(value, skey []byte) (token []byte, err error) {
+	return Trapdoor(value, ValueKey(skey))
+}
@@ -0,0 +1,251 @@
+// Large files are encrypted as a stream of fixed-size authenticated chunks, so that neither side
+// needs the whole plaintext in memory. The stream starts with a header:
+// magic(4)||version(1)||chunk size(4)||salt(16)
+// Each stream is encrypted by its own key derived from the EKey and the random salt, and each chunk
+// is sealed by AES-GCM with nonce counter(11)||final(1). The header and the additional data are
+// authenticated with every chunk. Since the counter is in the nonce, reordered chunks fail to open,
+// and since only the last chunk is sealed as final, a truncated stream is detected at its end.
+
+package crypto
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	// DefaultChunkSize is the size of plaintext in each chunk
+	DefaultChunkSize = 64 << 10
+
+	// MaxChunkSize limits the memory of a reader
+	MaxChunkSize = 16 << 20
+
+	// StreamVersion is the version of stream header
+	StreamVersion byte = 0x01
+
+	streamSaltLen   = 16
+	streamHeaderLen = 4 + 1 + 4 + streamSaltLen
+)
+
+var streamMagic = []byte{0x67, 0x6e, 0x72, 0x73}
+
+var (
+	ErrBadStream    = errors.New("invalid stream header")
+	ErrStreamClosed = errors.New("stream has been closed")
+)
+
+// newStreamAEAD returns the cipher of a stream and the additional data of its chunks
+func newStreamAEAD(ekey, header, ad []byte) (cipher.AEAD, []byte, error) {
+	salt := header[streamHeaderLen-streamSaltLen:]
+	block, err := aes.NewCipher(DeriveKey(ekey, append([]byte("stream"), salt...), EKeyLen))
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gcm, append(append([]byte{}, header...), ad...), nil
+}
+
+// chunkNonce returns the nonce of the i-th chunk
+func chunkNonce(i uint64, final bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], i)
+	if final {
+		nonce[11] = 0x01
+	}
+	return nonce
+}
+
+type encryptWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	ad     []byte
+	buf    []byte
+	size   int
+	count  uint64
+	closed bool
+}
+
+// NewEncryptWriter returns a writer which encrypts everything written to w by a key derived
+// from ekey. The additional data, such as fileid, is needed again to decrypt the stream.
+// Close must be called to write the final chunk, and it does not close w
+func NewEncryptWriter(w io.Writer, ekey, ad []byte) (io.WriteCloser, error) {
+	return NewEncryptWriterSize(w, ekey, ad, DefaultChunkSize)
+}
+
+// NewEncryptWriterSize is NewEncryptWriter with the specified chunk size
+func NewEncryptWriterSize(w io.Writer, ekey, ad []byte, chunkSize int) (io.WriteCloser, error) {
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, ErrBadStream
+	}
+
+	salt, err := getRandom(streamSaltLen)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, 0, streamHeaderLen)
+	header = append(header, streamMagic...)
+	header = append(header, StreamVersion)
+	header = append(header, byte(chunkSize>>24), byte(chunkSize>>16), byte(chunkSize>>8), byte(chunkSize))
+	header = append(header, salt...)
+
+	aead, chunkAD, err := newStreamAEAD(ekey, header, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{
+		w:    w,
+		aead: aead,
+		ad:   chunkAD,
+		buf:  make([]byte, 0, chunkSize+aead.Overhead()),
+		size: chunkSize,
+	}, nil
+}
+
+// seal encrypts and writes the buffered chunk
+func (ew *encryptWriter) seal(final bool) error {
+	chunk := ew.aead.Seal(ew.buf[:0], chunkNonce(ew.count, final), ew.buf, ew.ad)
+	ew.count++
+	ew.buf = ew.buf[:0]
+	_, err := ew.w.Write(chunk)
+	return err
+}
+
+// Write implements io.Writer. A full chunk is kept until more data comes,
+// since only Close knows whether it is the final one
+func (ew *encryptWriter) Write(p []byte) (n int, err error) {
+	if ew.closed {
+		return 0, ErrStreamClosed
+	}
+
+	for len(p) > 0 {
+		if len(ew.buf) == ew.size {
+			if err = ew.seal(false); err != nil {
+				return n, err
+			}
+		}
+
+		m := ew.size - len(ew.buf)
+		if m > len(p) {
+			m = len(p)
+		}
+		ew.buf = append(ew.buf, p[:m]...)
+		p = p[m:]
+		n += m
+	}
+	return n, nil
+}
+
+// Close writes the final chunk
+func (ew *encryptWriter) Close() error {
+	if ew.closed {
+		return nil
+	}
+	ew.closed = true
+	return ew.seal(true)
+}
+
+type decryptReader struct {
+	r     *bufio.Reader
+	aead  cipher.AEAD
+	ad    []byte
+	chunk []byte
+	plain []byte
+	count uint64
+	err   error
+}
+
+// NewDecryptReader returns a reader which decrypts the stream generated by NewEncryptWriter.
+// Data is only returned after its chunk has been authenticated, and a truncated or reordered
+// stream ends with ErrAuthFailed instead of io.EOF
+func NewDecryptReader(r io.Reader, ekey, ad []byte) (io.Reader, error) {
+	header := make([]byte, streamHeaderLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrBadStream
+	}
+	if !bytes.Equal(header[:4], streamMagic) || header[4] != StreamVersion {
+		return nil, ErrBadStream
+	}
+
+	chunkSize := int(binary.BigEndian.Uint32(header[5:9]))
+	if chunkSize <= 0 || chunkSize > MaxChunkSize {
+		return nil, ErrBadStream
+	}
+
+	aead, chunkAD, err := newStreamAEAD(ekey, header, ad)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{
+		r:     bufio.NewReader(r),
+		aead:  aead,
+		ad:    chunkAD,
+		chunk: make([]byte, chunkSize+aead.Overhead()),
+	}, nil
+}
+
+// next reads and opens the next chunk
+func (dr *decryptReader) next() error {
+	n, err := io.ReadFull(dr.r, dr.chunk)
+	final := false
+	switch err {
+	case nil:
+		// a full chunk is the final one if nothing follows
+		if _, err = dr.r.Peek(1); err == io.EOF {
+			final = true
+		} else if err != nil {
+			return err
+		}
+	case io.EOF, io.ErrUnexpectedEOF:
+		final = true
+	default:
+		return err
+	}
+
+	if n < dr.aead.Overhead() {
+		return ErrAuthFailed
+	}
+
+	plain, err := dr.aead.Open(dr.chunk[:0], chunkNonce(dr.count, final), dr.chunk[:n], dr.ad)
+	if err != nil {
+		return ErrAuthFailed
+	}
+	dr.count++
+	dr.plain = plain
+
+	if final {
+		return io.EOF
+	}
+	return nil
+}
+
+// Read implements io.Reader
+func (dr *decryptReader) Read(p []byte) (n int, err error) {
+	for len(dr.plain) == 0 {
+		if dr.err != nil {
+			return 0, dr.err
+		}
+		dr.err = dr.next()
+		if dr.err != nil && dr.err != io.EOF {
+			dr.plain = nil
+		}
+	}
+
+	n = copy(p, dr.plain)
+	dr.plain = dr.plain[n:]
+	return n, nil
+}
@@ -0,0 +1,101 @@
+package crypto
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func encryptStream(t *testing.T, key, ad, msg []byte, chunkSize int) []byte {
+	var buf bytes.Buffer
+	w, err := NewEncryptWriterSize(&buf, key, ad, chunkSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// write in odd pieces
+	for i := 0; i < len(msg); i += 7 {
+		end := i + 7
+		if end > len(msg) {
+			end = len(msg)
+		}
+		if _, err = w.Write(msg[i:end]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(key, ad, ct []byte) ([]byte, error) {
+	r, err := NewDecryptReader(bytes.NewReader(ct), key, ad)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func TestStreamEncDec(t *testing.T) {
+	key := KeyDerivFunc(KeyGen(), SaltGen(), EKeyLen)
+	ad := []byte("fileid")
+
+	for _, size := range []int{0, 1, 31, 32, 33, 64, 100, 1000} {
+		msg := randomBytes(uint32(size))
+		ct := encryptStream(t, key, ad, msg, 32)
+
+		plain, err := decryptStream(key, ad, ct)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(plain, msg) {
+			t.Errorf("size %d: plaintext mismatch", size)
+		}
+
+		if _, err = decryptStream(key, []byte("other"), ct); err != ErrAuthFailed {
+			t.Errorf("size %d: wrong additional data should fail, got %v", size, err)
+		}
+	}
+}
+
+func TestStreamTamper(t *testing.T) {
+	key := KeyDerivFunc(KeyGen(), SaltGen(), EKeyLen)
+	msg := randomBytes(100)
+	ct := encryptStream(t, key, nil, msg, 32)
+
+	// 4 chunks of 48 bytes after the header, the last one has 4 bytes of plaintext
+	chunk := 32 + 16
+	if len(ct) != streamHeaderLen+3*chunk+4+16 {
+		t.Fatalf("unexpected stream length %d", len(ct))
+	}
+
+	// truncated at a chunk boundary
+	if _, err := decryptStream(key, nil, ct[:streamHeaderLen+2*chunk]); err != ErrAuthFailed {
+		t.Errorf("truncated stream should fail, got %v", err)
+	}
+
+	// the final chunk dropped
+	if _, err := decryptStream(key, nil, ct[:streamHeaderLen+3*chunk]); err != ErrAuthFailed {
+		t.Errorf("stream without final chunk should fail, got %v", err)
+	}
+
+	// only the header
+	if _, err := decryptStream(key, nil, ct[:streamHeaderLen]); err != ErrAuthFailed {
+		t.Errorf("empty stream should fail, got %v", err)
+	}
+
+	// the first two chunks swapped
+	swapped := append([]byte{}, ct...)
+	copy(swapped[streamHeaderLen:], ct[streamHeaderLen+chunk:streamHeaderLen+2*chunk])
+	copy(swapped[streamHeaderLen+chunk:], ct[streamHeaderLen:streamHeaderLen+chunk])
+	if _, err := decryptStream(key, nil, swapped); err != ErrAuthFailed {
+		t.Errorf("reordered stream should fail, got %v", err)
+	}
+
+	// another chunk size in header
+	resized := append([]byte{}, ct...)
+	resized[8] = 48
+	if _, err := decryptStream(key, nil, resized); err != ErrAuthFailed {
+		t.Errorf("modified header should fail, got %v", err)
+	}
+}
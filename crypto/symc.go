@@ -10,6 +10,7 @@ import (
 	"crypto/rand"
 	"crypto/sha1"
 	"errors"
+	"fmt"
 	"github.com/ethereum/go-ethereum/crypto/sha3"
 	"io"
 	"bytes"
@@ -18,6 +19,26 @@ import (
 var (
 	// The length of symmetric encryption key
 	EKeyLen = 32
+
+	// ErrAuthFailed is returned when an authenticated ciphertext has been tampered
+	ErrAuthFailed = errors.New("message authentication failed")
+
+	// ErrBadPadding is returned when a CBC ciphertext has invalid length or padding
+	ErrBadPadding = errors.New("invalid ciphertext padding")
+
+	// ErrUnversioned is returned when a ciphertext without versioned header is given to DecryptAEAD
+	ErrUnversioned = errors.New("ciphertext has no versioned header")
+)
+
+// The header of versioned ciphertext is magic||version. Legacy CBC ciphertexts have no header,
+// and one of them is taken as versioned only if its random IV starts with the magic
+var cipherMagic = []byte{0x67, 0x6e, 0x72, 0x6f}
+
+const (
+	// CipherGCMv1 is the version of AES-GCM ciphertext generated by EncryptAEAD
+	CipherGCMv1 byte = 0x01
+
+	cipherHeaderLen = 5
 )
 
 // SHA1 returns the SHA1 hash of the input data
@@ -62,11 +83,23 @@ func pKCS7Padding(buffer []byte, blockSize int) []byte {
 	return append(buffer, padtext...)
 }
 
-// pKCS7UnPadding removes filled bytes
-func pKCS7UnPadding(buffer []byte) []byte {
+// pKCS7UnPadding removes filled bytes after checking all of them
+func pKCS7UnPadding(buffer []byte, blockSize int) ([]byte, error) {
 	length := len(buffer)
+	if length == 0 || length%blockSize != 0 {
+		return nil, ErrBadPadding
+	}
+
 	unpadding := int(buffer[length-1])
-	return buffer[:(length - unpadding)]
+	if unpadding == 0 || unpadding > blockSize {
+		return nil, ErrBadPadding
+	}
+	for _, b := range buffer[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, ErrBadPadding
+		}
+	}
+	return buffer[:(length - unpadding)], nil
 }
 
 
@@ -98,11 +131,96 @@ func AESDecryptCBC(key, ciphertext []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	// iv and at least one block of padded plaintext
+	if len(ciphertext) < 2*block.BlockSize() || len(ciphertext)%block.BlockSize() != 0 {
+		return nil, ErrBadPadding
+	}
+
 	plaintext := make([]byte, len(ciphertext)-block.BlockSize())
 
 	cfb := cipher.NewCBCDecrypter(block, ciphertext[:block.BlockSize()])
 	cfb.CryptBlocks(plaintext, ciphertext[block.BlockSize():])
-	return pKCS7UnPadding(plaintext), nil
+	return pKCS7UnPadding(plaintext, block.BlockSize())
+}
+
+// AESEncryptGCM generates an authenticated AES ciphertext using GCM pattern.
+// The additional data is authenticated but not encrypted
+func AESEncryptGCM(key, plaintext, ad []byte) (ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := getIV(gcm.NonceSize())
+	if err != nil {
+		return nil, errors.New("AESEncryptGCM: failed to generate nonce")
+	}
+
+	// ciphertext = nonce||cipher||tag
+	return gcm.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+// AESDecryptGCM returns the plaintext if both ciphertext and additional data are untouched
+func AESDecryptGCM(key, ciphertext, ad []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, ErrAuthFailed
+	}
+
+	nonce := ciphertext[:gcm.NonceSize()]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext[gcm.NonceSize():], ad)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return plaintext, nil
+}
+
+// IsVersioned reports whether the ciphertext starts with a versioned header
+func IsVersioned(ciphertext []byte) bool {
+	return len(ciphertext) >= cipherHeaderLen && bytes.Equal(ciphertext[:len(cipherMagic)], cipherMagic)
+}
+
+// EncryptAEAD generates a versioned authenticated ciphertext: magic||version||nonce||cipher||tag.
+// The header is authenticated together with the additional data
+func EncryptAEAD(key, plaintext, ad []byte) ([]byte, error) {
+	header := append(append([]byte{}, cipherMagic...), CipherGCMv1)
+
+	ciphertext, err := AESEncryptGCM(key, plaintext, append(header, ad...))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ciphertext...), nil
+}
+
+// DecryptAEAD decrypts the ciphertext generated by EncryptAEAD. A ciphertext without header is
+// rejected, since falling back to CBC would let anyone strip the header and tamper with it.
+// Legacy CBC ciphertexts must be decrypted by AESDecryptCBC explicitly
+func DecryptAEAD(key, ciphertext, ad []byte) ([]byte, error) {
+	if !IsVersioned(ciphertext) {
+		return nil, ErrUnversioned
+	}
+
+	header := ciphertext[:cipherHeaderLen]
+	switch header[len(cipherMagic)] {
+	case CipherGCMv1:
+		return AESDecryptGCM(key, ciphertext[cipherHeaderLen:], append(append([]byte{}, header...), ad...))
+	default:
+		return nil, fmt.Errorf("DecryptAEAD: ciphertext version %d not supported", header[len(cipherMagic)])
+	}
 }
 
 /*// AESEncryptOFB generates an AES ciphertext using OFB pattern
@@ -70,3 +70,102 @@ func TestEncryptDecrypt(t *testing.T) {
 		fmt.Println("This test failed!")
 	}
 }
+
+func TestEncryptDecryptGCM(t *testing.T) {
+	key := KeyDerivFunc(KeyGen(), SaltGen(), EKeyLen)
+
+	msg := []byte("Genaro Network")
+	ad := []byte("fileid")
+
+	ciphertext, err := AESEncryptGCM(key, msg, ad)
+	if err != nil {
+		panic(err)
+	}
+
+	plaintext, err := AESDecryptGCM(key, ciphertext, ad)
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(msg, plaintext) {
+		t.Errorf("plaintext not equal to message")
+	}
+
+	// the additional data is bound to the ciphertext
+	if _, err = AESDecryptGCM(key, ciphertext, []byte("another fileid")); err != ErrAuthFailed {
+		t.Errorf("ciphertext opened with wrong additional data")
+	}
+
+	// tampered ciphertext
+	ciphertext[len(ciphertext)-1] ^= 0x01
+	if _, err = AESDecryptGCM(key, ciphertext, ad); err != ErrAuthFailed {
+		t.Errorf("tampered ciphertext opened")
+	}
+}
+
+func TestEncryptDecryptAEAD(t *testing.T) {
+	key := KeyDerivFunc(KeyGen(), SaltGen(), EKeyLen)
+
+	msg := []byte("Genaro Network")
+	ad := []byte("fileid")
+
+	ciphertext, err := EncryptAEAD(key, msg, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsVersioned(ciphertext) {
+		t.Errorf("ciphertext has no header")
+	}
+
+	plaintext, err := DecryptAEAD(key, ciphertext, ad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(msg, plaintext) {
+		t.Errorf("plaintext mismatch")
+	}
+
+	if _, err = DecryptAEAD(key, ciphertext, []byte("other")); err != ErrAuthFailed {
+		t.Errorf("wrong additional data should fail, got %v", err)
+	}
+
+	// a downgraded version must not be accepted
+	downgraded := append([]byte{}, ciphertext...)
+	downgraded[cipherHeaderLen-1] = 0x02
+	if _, err = DecryptAEAD(key, downgraded, ad); err == nil {
+		t.Errorf("unknown version should fail")
+	}
+
+	// a stripped header must not downgrade to CBC
+	if _, err = DecryptAEAD(key, ciphertext[cipherHeaderLen:], ad); err != ErrUnversioned {
+		t.Errorf("ciphertext without header should fail, got %v", err)
+	}
+	legacy, err := AESEncryptCBC(key, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecryptAEAD(key, legacy, ad); err != ErrUnversioned {
+		t.Errorf("legacy ciphertext should fail, got %v", err)
+	}
+}
+
+func TestCBCBadPadding(t *testing.T) {
+	key := KeyDerivFunc(KeyGen(), SaltGen(), EKeyLen)
+
+	ciphertext, err := AESEncryptCBC(key, []byte("Genaro Network"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = AESDecryptCBC(key, ciphertext[:len(ciphertext)-1]); err != ErrBadPadding {
+		t.Errorf("truncated ciphertext should fail, got %v", err)
+	}
+	if _, err = AESDecryptCBC(key, ciphertext[:16]); err != ErrBadPadding {
+		t.Errorf("ciphertext without block should fail, got %v", err)
+	}
+
+	// flipping the last byte of iv breaks the padding of a one block message
+	ciphertext[15] ^= 0xff
+	if _, err = AESDecryptCBC(key, ciphertext); err != ErrBadPadding {
+		t.Errorf("tampered padding should fail, got %v", err)
+	}
+}
@@ -6,6 +6,10 @@ package genaro_crypto
 
 //PBKDF2 Key Derivation Function：golang.org/x/crypto/pbkdf2
 
+//Scrypt Key Derivation Function for keystore：golang.org/x/crypto/scrypt
+
+//HKDF Key Derivation Function for sub keys：golang.org/x/crypto/hkdf
+
 //MongoDB：labix.org/v2/mgo; labix.org/v2/mgo/bson
 
 //Google Protocol Buffer: github.com/golang/protobuf/proto
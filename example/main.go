@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 
+	"genaro-crypto/kdc"
+
 	"gopkg.in/mgo.v2"
 )
 
@@ -26,9 +28,11 @@ var (
 	EkvDB = "EnKeyValues"
 )
 
-// the storage format of encrypted key-values in database
+// the storage format of encrypted key-values in database, the record is
+// serialized by client.MarshalKeyValue and the pub is kept for queries
 type EkeyValue struct {
-	Pub, SSEKey, EKey, EValue string
+	Pub    string
+	Record []byte
 }
 
 // the format of search token
@@ -38,6 +42,7 @@ type Token struct {
 
 type EValues struct {
 	Pub []byte
+	EKs [][]byte
 	EVs [][]byte
 }
 
@@ -86,6 +91,9 @@ var keyword = "devDependencies.new.url-loader"
 
 func main() {
 
+	// master keys are wrapped by the test KEK
+	kdc.KEK = kdc.FileKEK(keypairspath + "/kek")
+
 	//All test functions see test.go
 
 	TestCoreFunctions()
@@ -30,6 +30,7 @@ func EncContract(fileid []byte, keys KeyWithPub, kvs map[string]string) (num int
 	ekey, _ := hex.DecodeString(keys.EKey)
 	skey, _ := hex.DecodeString(keys.SKey)
 
+	pub, _ := hex.DecodeString(keys.Pub)
 	bkey := &kdc.SubKey{
 		EKey: ekey,
 		SKey: skey,
@@ -40,16 +41,17 @@ func EncContract(fileid []byte, keys KeyWithPub, kvs map[string]string) (num int
 			Key:   []byte(key),
 			Value: []byte(value),
 		}
-		ekv, err := client.EncryptKeyValue(bkey, kv)
+		ekv, err := client.EncryptKeyValue(bkey, fileid, pub, kv)
 		if err != nil {
 			return num, err
 		}
 
-		ssek := hex.EncodeToString(ekv.SSEKey)
-		ek := hex.EncodeToString(ekv.EKey)
-		ev := hex.EncodeToString(ekv.EValue)
+		rec, err := client.MarshalKeyValue(ekv, pub, 0)
+		if err != nil {
+			return num, err
+		}
 
-		err = ec.Insert(&EkeyValue{keys.Pub, ssek, ek, ev})
+		err = ec.Insert(&EkeyValue{keys.Pub, rec})
 		if err != nil {
 			return num, err
 		}
@@ -81,12 +83,9 @@ func DecWithPrint(fileid []byte, keys []KeyWithPub) (num int, err error) {
 		}
 
 		for _, ele := range ekvs {
-			ek, _ := hex.DecodeString(ele.EKey)
-			ev, _ := hex.DecodeString(ele.EValue)
-
-			ekv := &client.EnKeyValue{
-				EKey:   ek,
-				EValue: ev,
+			ekv, _, _, err := client.UnmarshalKeyValue(ele.Record)
+			if err != nil {
+				return num, err
 			}
 
 			ekey, _ := hex.DecodeString(key.EKey)
@@ -94,7 +93,8 @@ func DecWithPrint(fileid []byte, keys []KeyWithPub) (num int, err error) {
 				EKey: ekey,
 			}
 
-			kv, err := client.DecryptKeyValue(dek, ekv)
+			pub, _ := hex.DecodeString(key.Pub)
+			kv, err := client.DecryptKeyValue(dek, fileid, pub, ekv)
 			if err != nil {
 				return num, err
 			}
@@ -129,19 +129,23 @@ func Search(tokens []Token, fileid []byte) (evs []EValues, num int, err error) {
 			return nil, num, errors.New("something wrong with ekvs search")
 		}
 
-		var temp [][]byte
+		var temp, keys [][]byte
 		for _, ele := range ekvs {
-			ssek, _ := hex.DecodeString(ele.SSEKey)
-			ev, _ := hex.DecodeString(ele.EValue)
+			ekv, _, _, err := client.UnmarshalKeyValue(ele.Record)
+			if err != nil {
+				return nil, num, err
+			}
 
-			if crypto.Matching(token.Token, ssek) {
-				temp = append(temp, ev)
+			if crypto.Matching(token.Token, ekv.SSEKey) {
+				temp = append(temp, ekv.EValue)
+				keys = append(keys, ekv.EKey)
 				num++
 			}
 		}
 		if temp != nil {
 			evl := EValues{
 				Pub: token.Pub,
+				EKs: keys,
 				EVs: temp,
 			}
 			evs = append(evs, evl)
@@ -150,22 +154,25 @@ func Search(tokens []Token, fileid []byte) (evs []EValues, num int, err error) {
 	return
 }
 
-// decrypt encrypted values
-func DecEValues(keys []KeyWithPub, evs []EValues) (vs [][]byte, num int, err error) {
+// decrypt encrypted values, whose keys are needed to authenticate them
+func DecEValues(fileid []byte, keys []KeyWithPub, evs []EValues) (vs [][]byte, num int, err error) {
 	num = 0
 
 	for _, key := range keys {
 		pub, _ := hex.DecodeString(key.Pub)
 		k0, _ := hex.DecodeString(key.EKey)
+		dek := &kdc.SubKey{
+			EKey: k0,
+		}
 
 		for _, ev := range evs {
 			if bytes.Equal(pub, ev.Pub) {
-				for _, ele := range ev.EVs {
-					v, err := crypto.AESDecryptCBC(k0, ele)
+				for i, ele := range ev.EVs {
+					kv, err := client.DecryptKeyValue(dek, fileid, pub, &client.EnKeyValue{EKey: ev.EKs[i], EValue: ele})
 					if err != nil {
 						return vs, num, err
 					}
-					vs = append(vs, v)
+					vs = append(vs, kv.Value)
 					num++
 				}
 			}
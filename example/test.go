@@ -142,7 +142,7 @@ func TestSearch() {
 	}
 	fmt.Printf("%d encrypted values for %s have been found\n", nums, keyword)
 
-	vs, numd, err := DecEValues(testkeys, evs)
+	vs, numd, err := DecEValues(id, testkeys, evs)
 	if err != nil {
 		panic(err)
 	}
@@ -514,7 +514,7 @@ func TestSearchContract(keyword string) {
 	}
 	fmt.Printf("%d encrypted values for %s have been found\n", nums, keyword)
 
-	vs, numd, err := DecEValues(kws, evs)
+	vs, numd, err := DecEValues(fileid, kws, evs)
 	if err != nil {
 		panic(err)
 	}
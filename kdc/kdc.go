@@ -5,8 +5,9 @@
 // So the superuser list must be maintained very carefully. The whitelist is a list of public
 // keys along with the contract fileid they can maintain.
 
-// KDC uses MongoDB as database management system. In real-world deployment, the sensitive
-// data associated with the keys is recommended to be encrypted by MongoDB.
+// KDC uses MongoDB as database management system. The master keys are wrapped by a
+// key-encryption key before they are stored (see kek.go). In real-world deployment, the other
+// sensitive data associated with the keys is recommended to be encrypted by MongoDB.
 
 package kdc
 
@@ -15,7 +16,10 @@ import (
 	"errors"
 	"fmt"
 	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
@@ -48,6 +52,13 @@ var (
 	OldCol = "outdatedlist"
 )
 
+// Roles of the public keys in a whitelist snapshot
+const (
+	RoleOwner      uint32 = 1 // creator of the contract
+	RoleMaintainer uint32 = 2 // public key in whitelist
+	RolePolicy     uint32 = 3 // public key admitted by the access policy
+)
+
 var (
 	ErrPubExist = fmt.Errorf("the added pub has existed in whitelist")
 	ErrNoAccess = fmt.Errorf("permission denied")
@@ -60,6 +71,15 @@ type SuperUser struct {
 
 type Msk struct {
 	File, Key, Owner string
+
+	// Wrapped reports whether the Key is wrapped by KEK. The legacy records
+	// store the master key in plaintext until they are migrated
+	Wrapped bool
+
+	// Kdf identifies the derivation of sub keys, and the legacy records without it use
+	// PBKDF2. Epoch is the key epoch of the master key (see kdf.go)
+	Kdf   string
+	Epoch uint64
 }
 
 type Salt struct {
@@ -75,6 +95,17 @@ type WhiteList struct {
 	File  string
 	Owner string
 	List  []string
+
+	// Epoch is the key epoch of the contract, and Version is increased
+	// every time the whitelist is changed
+	Epoch, Version uint64
+
+	// Scopes are the path prefixes of the public keys which are limited to
+	// subtrees, a public key without scopes has full access (see pathkey.go)
+	Scopes map[string][]string
+
+	// Policy is the attribute-based access policy in addition to the whitelist (see policy.go)
+	Policy string
 }
 
 type KeyOwner struct {
@@ -125,7 +156,7 @@ func ReturnAllKeys(msd *mgo.Database, sud *mgo.Database,
 	// Check whether the pub is the owner of fileid
 	msc := msd.C(MskCol)
 	result := new(Msk)
-	msk := make([]byte, crypto.MskLen)
+	var msk *MasterKey
 
 	err = msc.Find(bson.M{"file": file, "owner": owner}).One(result)
 	if err != nil {
@@ -138,29 +169,41 @@ func ReturnAllKeys(msd *mgo.Database, sud *mgo.Database,
 			return nil, ErrNoFileid
 		}
 	} else {
-		msk, _ = hex.DecodeString(result.Key)
+		msk, err = result.open()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	// return all keys
-	sac := sad.C(file)
+	ko, err = SubKeysOf(sad, msk, fileid)
+	if err != nil {
+		return nil, errors.New("ReturnAllKeys: something wrong with salts search")
+	}
+	return
+}
+
+// SubKeysOf returns the sub keys of every public key which has called for keys of the file
+func SubKeysOf(sad *mgo.Database, msk *MasterKey, fileid []byte) (ko []*KeyOwner, err error) {
+	sac := sad.C(hex.EncodeToString(fileid))
 	var salts []Salt
 
 	err = sac.Find(bson.M{}).All(&salts)
 	if err != nil {
-		return nil, errors.New("ReturnAllKeys: something wrong with salts search")
+		return nil, err
 	}
 
 	for _, salt := range salts {
 		esalt, ssalt := salt.toBytes()
-		subk := SubKey{
-			EKey: crypto.KeyDerivFunc(msk, esalt, crypto.EKeyLen),
-			SKey: crypto.KeyDerivFunc(msk, ssalt, crypto.SKeyLen),
+		ow, _ := hex.DecodeString(salt.Pub)
+		subk, err := msk.subKey(fileid, ow, esalt, ssalt)
+		if err != nil {
+			return nil, err
 		}
 
-		ow, _ := hex.DecodeString(salt.Pub)
 		ele := &KeyOwner{
 			Pub:    ow,
-			SubKey: subk,
+			SubKey: *subk,
 		}
 		ko = append(ko, ele)
 	}
@@ -168,7 +211,7 @@ func ReturnAllKeys(msd *mgo.Database, sud *mgo.Database,
 }
 
 // GetMasterKey returns the master key corresponding to the input fileid
-func GetMasterKey(d *mgo.Database, fileid []byte) (msk []byte, err error) {
+func GetMasterKey(d *mgo.Database, fileid []byte) (msk *MasterKey, err error) {
 	file := hex.EncodeToString(fileid)
 
 	// return the named collection
@@ -178,29 +221,59 @@ func GetMasterKey(d *mgo.Database, fileid []byte) (msk []byte, err error) {
 	if err != nil {
 		return nil, err
 	}
+	return result.open()
+}
 
-	msk, _ = hex.DecodeString(result.Key)
-	return
+// masterKey returns the plaintext of master key in the record
+func (m *Msk) masterKey() ([]byte, error) {
+	key, err := hex.DecodeString(m.Key)
+	if err != nil {
+		return nil, err
+	}
+	if !m.Wrapped {
+		return key, nil
+	}
+
+	fileid, _ := hex.DecodeString(m.File)
+	msk, err := unwrapKey(KEK, fileid, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap master key with error: %s", err.Error())
+	}
+	return msk, nil
 }
 
-// GenMasterKey generates a master key for the file
-func GenMasterKey(d *mgo.Database, fileid, owner []byte) (msk []byte, err error) {
+// GenMasterKey generates a master key for the file, whose sub keys are derived by DefaultKdf
+func GenMasterKey(d *mgo.Database, fileid, owner []byte) (msk *MasterKey, err error) {
 	// judge whether the msk exists already
 	msk, err = GetMasterKey(d, fileid)
 	if msk != nil {
 		return msk, nil
 	}
+	if err != mgo.ErrNotFound {
+		// the msk exists but cannot be unwrapped
+		return nil, err
+	}
 
 	// Generate 16-byte msk
-	msk = crypto.KeyGen()
+	msk = &MasterKey{
+		Key:   crypto.KeyGen(),
+		Kdf:   DefaultKdf,
+		Epoch: FirstEpoch,
+	}
+
+	// wrap msk by KEK
+	wrapped, err := wrapKey(KEK, fileid, msk.Key)
+	if err != nil {
+		return nil, err
+	}
 
 	file := hex.EncodeToString(fileid)
-	key := hex.EncodeToString(msk)
+	key := hex.EncodeToString(wrapped)
 	ow := hex.EncodeToString(owner)
 
 	c := d.C(MskCol)
 
-	err = c.Insert(&Msk{file, key, ow})
+	err = c.Insert(&Msk{file, key, ow, true, msk.Kdf, msk.Epoch})
 	if err != nil {
 		return nil, err
 	}
@@ -236,17 +309,13 @@ func (sa *Salt) fromBytes(pub, esalt, ssalt []byte) {
 }
 
 // GenSubKey generates sub keys of the file for the public key
-func GenSubKey(d *mgo.Database, msk, fileid, pub []byte) (subk *SubKey, err error) {
-	subk = new(SubKey)
-
+func GenSubKey(d *mgo.Database, msk *MasterKey, fileid, pub []byte) (subk *SubKey, err error) {
 	// judge whether the salt exists already
 	sa := new(Salt)
 	sa, err = GetSalts(d, fileid, pub)
 	if sa != nil {
 		esalt, ssalt := sa.toBytes()
-		subk.EKey = crypto.KeyDerivFunc(msk, esalt, crypto.EKeyLen)
-		subk.SKey = crypto.KeyDerivFunc(msk, ssalt, crypto.SKeyLen)
-		return
+		return msk.subKey(fileid, pub, esalt, ssalt)
 	}
 
 	// generate salts
@@ -266,9 +335,7 @@ func GenSubKey(d *mgo.Database, msk, fileid, pub []byte) (subk *SubKey, err erro
 	}
 
 	// generate sub keys
-	subk.EKey = crypto.KeyDerivFunc(msk, esalt, crypto.EKeyLen)
-	subk.SKey = crypto.KeyDerivFunc(msk, ssalt, crypto.SKeyLen)
-	return
+	return msk.subKey(fileid, pub, esalt, ssalt)
 }
 
 // SaveWhitelist saves whitelist into database
@@ -276,21 +343,45 @@ func SaveWhitelist(d *mgo.Database, fileid, owner []byte, list [][]byte) error {
 	file := hex.EncodeToString(fileid)
 	ow := hex.EncodeToString(owner)
 
-	var wil []string
-	for _, pub := range list {
-		wil = append(wil, hex.EncodeToString(pub))
+	wil, scopes, err := parseList(list)
+	if err != nil {
+		return err
 	}
 
 	// return whitelist collection
 	c := d.C(WilCol)
 
-	err := c.Insert(&WhiteList{file, ow, wil})
+	err = c.Insert(&WhiteList{
+		File:    file,
+		Owner:   ow,
+		List:    wil,
+		Epoch:   FirstEpoch,
+		Version: 1,
+		Scopes:  scopes,
+	})
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
+// parseList decodes the whitelist entries into the public keys along with their path prefixes
+func parseList(list [][]byte) (wil []string, scopes map[string][]string, err error) {
+	scopes = make(map[string][]string)
+	for _, entry := range list {
+		pub, prefixes, err := ParseEntry(entry)
+		if err != nil {
+			return nil, nil, err
+		}
+		sn := hex.EncodeToString(pub)
+		wil = append(wil, sn)
+		if prefixes != nil {
+			scopes[sn] = prefixes
+		}
+	}
+	return wil, scopes, nil
+}
+
 // CheckWhitelist checks whether the public key is the owner of the file or in the whitelist
 func CheckWhitelist(d *mgo.Database, fileid, pub []byte) bool {
 	file := hex.EncodeToString(fileid)
@@ -307,6 +398,57 @@ func CheckWhitelist(d *mgo.Database, fileid, pub []byte) bool {
 	return false
 }
 
+// GetWhitelist returns the whitelist record of the file
+func GetWhitelist(d *mgo.Database, fileid []byte) (wl *WhiteList, err error) {
+	file := hex.EncodeToString(fileid)
+
+	// return whitelist collection
+	c := d.C(WilCol)
+	wl = new(WhiteList)
+	err = c.Find(bson.M{"file": file}).One(wl)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+// TakeSnapshot returns the owner, whitelist, epoch and version of the file at present.
+// The access policy and the trusted issuers are included as well, so that the users
+// admitted by policy can be checked against their credentials
+func TakeSnapshot(d *mgo.Database, fileid []byte) (*protobuf.Snapshot, error) {
+	wl, err := GetWhitelist(d, fileid)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, _ := hex.DecodeString(wl.Owner)
+	members := []*protobuf.SnapshotMember{{
+		Pub:  owner,
+		Role: proto.Uint32(RoleOwner),
+	}}
+	for _, sn := range wl.List {
+		pub, _ := hex.DecodeString(sn)
+		members = append(members, &protobuf.SnapshotMember{
+			Pub:  pub,
+			Role: proto.Uint32(RoleMaintainer),
+		})
+	}
+
+	snap := &protobuf.Snapshot{
+		Fileid:  fileid,
+		Owner:   owner,
+		Members: members,
+		Epoch:   proto.Uint64(wl.Epoch),
+		Version: proto.Uint64(wl.Version),
+		Stamp:   proto.Int64(time.Now().Unix()),
+	}
+	if wl.Policy != "" {
+		snap.Policy = proto.String(wl.Policy)
+		snap.Issuers = Issuers
+	}
+	return snap, nil
+}
+
 // UpdateWhitelist adds new public key into whitelist
 func UpdateWhitelist(c *mgo.Collection, fileid, pub []byte) error {
 
@@ -322,6 +464,8 @@ func UpdateWhitelist(c *mgo.Collection, fileid, pub []byte) error {
 	err = c.Update(bson.M{"file": file},
 		bson.M{"$push": bson.M{
 			"list": sn,
+		}, "$inc": bson.M{
+			"version": 1,
 		}})
 	if err != nil {
 		return err
@@ -118,6 +118,27 @@ func TestCheckWhitelist(t *testing.T) {
 	fmt.Println(CheckWhitelist(db, id, test4))
 }
 
+func TestTakeSnapshot(t *testing.T) {
+	session, err := mgo.Dial("localhost")
+	if err != nil {
+		panic(err)
+	}
+	defer session.Close()
+
+	db := session.DB(testDB)
+
+	id, _ := hex.DecodeString(testid)
+
+	snap, err := TakeSnapshot(db, id)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(snap.GetEpoch(), snap.GetVersion())
+	for _, m := range snap.Members {
+		fmt.Println(m.GetRole(), hex.EncodeToString(m.Pub))
+	}
+}
+
 func TestAddOldList(t *testing.T) {
 	session, err := mgo.Dial("localhost")
 	if err != nil {
@@ -194,6 +215,55 @@ func TestKeyGenAndReturn(t *testing.T) {
 
 }
 
+func TestRotateEpoch(t *testing.T) {
+	session, err := mgo.Dial("localhost")
+	if err != nil {
+		panic(err)
+	}
+	defer session.Close()
+
+	db := session.DB(testDB)
+
+	id, _ := hex.DecodeString(testid)
+	pub1, _ := hex.DecodeString(whitelist[0])
+
+	msk, err := GetMasterKey(db, id)
+	if err != nil {
+		panic(err)
+	}
+	before, err := GenSubKey(db, msk, id, pub1)
+	if err != nil {
+		panic(err)
+	}
+
+	epoch, err := RotateEpoch(db, db, id)
+	if err != nil {
+		panic(err)
+	}
+	if epoch != msk.Epoch+1 {
+		t.Errorf("epoch %d, want %d", epoch, msk.Epoch+1)
+	}
+
+	// the whitelist follows the master key
+	snap, _ := TakeSnapshot(db, id)
+	if snap.GetEpoch() != epoch {
+		t.Errorf("whitelist epoch %d, want %d", snap.GetEpoch(), epoch)
+	}
+	msk, _ = GetMasterKey(db, id)
+	if msk.Epoch != epoch {
+		t.Errorf("master key epoch %d, want %d", msk.Epoch, epoch)
+	}
+
+	// the sub keys of the new epoch differ
+	after, err := GenSubKey(db, msk, id, pub1)
+	if err != nil {
+		panic(err)
+	}
+	if hex.EncodeToString(after.EKey) == hex.EncodeToString(before.EKey) {
+		t.Errorf("sub keys are not rotated")
+	}
+}
+
 func printSubKey(key *SubKey) {
 	fmt.Println("EKey:" + hex.EncodeToString(key.EKey))
 	fmt.Println("Skey:" + hex.EncodeToString(key.SKey))
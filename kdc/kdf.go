@@ -0,0 +1,134 @@
+// The sub keys of a maintainer are derived from the master key of the contract and the salts of the
+// maintainer. Old contracts derive them by PBKDF2, which runs 10,000 iterations for each key on every
+// request, although stretching a uniformly random master key buys nothing. New contracts derive them
+// by HKDF-SHA256, and the info separates the domains of keys:
+// info = "genaro-subkey"||len||fileid||len||pub||len||purpose||epoch
+// where each length is 4-byte and the epoch is 8-byte. The KDF is recorded along with the master key,
+// so that the sub keys of a contract never change within an epoch.
+
+// A contract starts at FirstEpoch, and RotateEpoch moves its master key and whitelist to the next
+// epoch together, so that the sub keys issued from then on differ from the ones of the past epochs.
+// The keys of the past epochs are issued no more, so the records written in them stay readable only
+// by the holders of those keys. The legacy contracts derived by PBKDF2 have no epoch to rotate.
+
+package kdc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"genaro-crypto/crypto"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Identifiers of the derivation of sub keys
+const (
+	KdfPBKDF2 = ""
+	KdfHKDF   = "hkdf-sha256"
+)
+
+// FirstEpoch is the key epoch of new contracts
+const FirstEpoch = 1
+
+// DefaultKdf is the derivation of sub keys for new contracts
+var DefaultKdf = KdfHKDF
+
+var (
+	ErrUnknownKdf = errors.New("unknown key derivation function")
+	ErrNoEpoch    = errors.New("sub keys derived by PBKDF2 have no epoch to rotate")
+)
+
+// MasterKey is the plaintext master key of a contract along with the parameters of sub key derivation
+type MasterKey struct {
+	Key   []byte
+	Kdf   string
+	Epoch uint64
+}
+
+// open returns the master key in the record
+func (m *Msk) open() (*MasterKey, error) {
+	key, err := m.masterKey()
+	if err != nil {
+		return nil, err
+	}
+	return &MasterKey{
+		Key:   key,
+		Kdf:   m.Kdf,
+		Epoch: m.Epoch,
+	}, nil
+}
+
+// subKeyInfo assembles the HKDF info of the sub key for the purpose
+func subKeyInfo(fileid, pub []byte, purpose string, epoch uint64) []byte {
+	info := []byte("genaro-subkey")
+	for _, p := range [][]byte{fileid, pub, []byte(purpose)} {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(p)))
+		info = append(info, size[:]...)
+		info = append(info, p...)
+	}
+
+	var e [8]byte
+	binary.BigEndian.PutUint64(e[:], epoch)
+	return append(info, e[:]...)
+}
+
+// subKey derives the sub keys of the public key from its salts by the KDF of master key
+func (mk *MasterKey) subKey(fileid, pub, esalt, ssalt []byte) (*SubKey, error) {
+	switch mk.Kdf {
+	case KdfPBKDF2:
+		return &SubKey{
+			EKey: crypto.KeyDerivFunc(mk.Key, esalt, crypto.EKeyLen),
+			SKey: crypto.KeyDerivFunc(mk.Key, ssalt, crypto.SKeyLen),
+		}, nil
+	case KdfHKDF:
+		return &SubKey{
+			EKey: crypto.HKDF(mk.Key, esalt, subKeyInfo(fileid, pub, "ekey", mk.Epoch), crypto.EKeyLen),
+			SKey: crypto.HKDF(mk.Key, ssalt, subKeyInfo(fileid, pub, "skey", mk.Epoch), crypto.SKeyLen),
+		}, nil
+	}
+	return nil, ErrUnknownKdf
+}
+
+// RotateEpoch moves the contract to the next key epoch, and returns the new epoch. The epoch of the
+// master key is increased first, and the whitelist follows it, so that calling it again completes
+// a rotation which was interrupted in between
+func RotateEpoch(msd, wld *mgo.Database, fileid []byte) (uint64, error) {
+	file := hex.EncodeToString(fileid)
+
+	result := new(Msk)
+	err := msd.C(MskCol).Find(bson.M{"file": file}).One(result)
+	if err != nil {
+		return 0, err
+	}
+	if result.Kdf == KdfPBKDF2 {
+		return 0, ErrNoEpoch
+	}
+	wl, err := GetWhitelist(wld, fileid)
+	if err != nil {
+		return 0, err
+	}
+
+	epoch := result.Epoch
+	if wl.Epoch >= epoch {
+		epoch++
+		err = msd.C(MskCol).Update(bson.M{"file": file, "epoch": result.Epoch},
+			bson.M{"$set": bson.M{"epoch": epoch}})
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	err = wld.C(WilCol).Update(bson.M{"file": file},
+		bson.M{"$set": bson.M{
+			"epoch": epoch,
+		}, "$inc": bson.M{
+			"version": 1,
+		}})
+	if err != nil {
+		return 0, err
+	}
+	return epoch, nil
+}
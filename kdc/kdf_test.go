@@ -0,0 +1,58 @@
+package kdc
+
+import (
+	"bytes"
+	"genaro-crypto/crypto"
+	"testing"
+)
+
+func TestSubKeyKdf(t *testing.T) {
+	fileid := []byte("fileid")
+	pub1 := bytes.Repeat([]byte{0x01}, pubLen)
+	pub2 := bytes.Repeat([]byte{0x02}, pubLen)
+	esalt, ssalt := crypto.SaltGen(), crypto.SaltGen()
+
+	// the legacy records keep PBKDF2
+	legacy := &MasterKey{Key: crypto.KeyGen()}
+	subk, err := legacy.subKey(fileid, pub1, esalt, ssalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(subk.EKey, crypto.KeyDerivFunc(legacy.Key, esalt, crypto.EKeyLen)) ||
+		!bytes.Equal(subk.SKey, crypto.KeyDerivFunc(legacy.Key, ssalt, crypto.SKeyLen)) {
+		t.Errorf("legacy sub keys differ from PBKDF2")
+	}
+
+	mk := &MasterKey{Key: legacy.Key, Kdf: KdfHKDF, Epoch: 1}
+	subk, err = mk.subKey(fileid, pub1, esalt, ssalt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := mk.subKey(fileid, pub1, esalt, ssalt)
+	if !bytes.Equal(subk.EKey, again.EKey) || !bytes.Equal(subk.SKey, again.SKey) {
+		t.Errorf("HKDF sub keys are not deterministic")
+	}
+	if len(subk.EKey) != crypto.EKeyLen || len(subk.SKey) != crypto.SKeyLen || bytes.Equal(subk.EKey, subk.SKey) {
+		t.Errorf("wrong HKDF sub keys")
+	}
+
+	// the sub keys are bound to the public key, the file and the epoch
+	other, _ := mk.subKey(fileid, pub2, esalt, ssalt)
+	if bytes.Equal(subk.EKey, other.EKey) {
+		t.Errorf("sub keys of different public keys collide")
+	}
+	other, _ = mk.subKey([]byte("another"), pub1, esalt, ssalt)
+	if bytes.Equal(subk.EKey, other.EKey) {
+		t.Errorf("sub keys of different files collide")
+	}
+	next := &MasterKey{Key: mk.Key, Kdf: KdfHKDF, Epoch: 2}
+	other, _ = next.subKey(fileid, pub1, esalt, ssalt)
+	if bytes.Equal(subk.EKey, other.EKey) {
+		t.Errorf("sub keys of different epochs collide")
+	}
+
+	unknown := &MasterKey{Key: mk.Key, Kdf: "md5"}
+	if _, err = unknown.subKey(fileid, pub1, esalt, ssalt); err != ErrUnknownKdf {
+		t.Errorf("expected ErrUnknownKdf, got %v", err)
+	}
+}
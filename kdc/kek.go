@@ -0,0 +1,151 @@
+// The master keys in MskDB are wrapped by a key-encryption key (KEK) with AES-GCM before
+// they are stored, and the fileid is bound to each wrapped key as additional data so that
+// the records cannot be swapped between contracts. The KEK never enters the database, it
+// is supplied by a KeyProvider, which can read it from a file, an environment variable or
+// any other source such as an HSM.
+
+package kdc
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// KEKLen is the length of key-encryption key
+const KEKLen = 32
+
+var (
+	ErrNoKEK  = errors.New("no key-encryption key is provided")
+	ErrBadKEK = fmt.Errorf("key-encryption key must be %d bytes", KEKLen)
+)
+
+// KeyProvider supplies the key-encryption key of kdc
+type KeyProvider interface {
+	KEK() ([]byte, error)
+}
+
+// KEK is the provider used to wrap and unwrap master keys.
+// By default the KEK is read from environment variable GENARO_KDC_KEK
+var KEK KeyProvider = EnvKEK("GENARO_KDC_KEK")
+
+// FileKEK reads a hex encoded KEK from the named file
+type FileKEK string
+
+// KEK implements KeyProvider
+func (path FileKEK) KEK() ([]byte, error) {
+	buf, err := ioutil.ReadFile(string(path))
+	if err != nil {
+		return nil, err
+	}
+	return decodeKEK(string(buf))
+}
+
+// EnvKEK reads a hex encoded KEK from the named environment variable
+type EnvKEK string
+
+// KEK implements KeyProvider
+func (name EnvKEK) KEK() ([]byte, error) {
+	val, ok := os.LookupEnv(string(name))
+	if !ok {
+		return nil, ErrNoKEK
+	}
+	return decodeKEK(val)
+}
+
+// StaticKEK holds the KEK in memory
+type StaticKEK []byte
+
+// KEK implements KeyProvider
+func (k StaticKEK) KEK() ([]byte, error) {
+	if len(k) != KEKLen {
+		return nil, ErrBadKEK
+	}
+	return k, nil
+}
+
+// KeyProviderFunc is an adapter to use ordinary functions as KeyProvider
+type KeyProviderFunc func() ([]byte, error)
+
+// KEK implements KeyProvider
+func (f KeyProviderFunc) KEK() ([]byte, error) {
+	return f()
+}
+
+func decodeKEK(s string) ([]byte, error) {
+	kek, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(kek) != KEKLen {
+		return nil, ErrBadKEK
+	}
+	return kek, nil
+}
+
+// wrapKey encrypts master key by the KEK of provider
+func wrapKey(p KeyProvider, fileid, msk []byte) ([]byte, error) {
+	if p == nil {
+		return nil, ErrNoKEK
+	}
+	kek, err := p.KEK()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.AESEncryptGCM(kek, msk, fileid)
+}
+
+// unwrapKey decrypts the wrapped master key by the KEK of provider
+func unwrapKey(p KeyProvider, fileid, wrapped []byte) ([]byte, error) {
+	if p == nil {
+		return nil, ErrNoKEK
+	}
+	kek, err := p.KEK()
+	if err != nil {
+		return nil, err
+	}
+	return crypto.AESDecryptGCM(kek, wrapped, fileid)
+}
+
+// MigrateMasterKeys wraps all the plaintext master keys in database by the KEK of provider,
+// and returns the number of migrated records
+func MigrateMasterKeys(d *mgo.Database, p KeyProvider) (num int, err error) {
+	c := d.C(MskCol)
+
+	var msks []Msk
+	err = c.Find(bson.M{"wrapped": bson.M{"$ne": true}}).All(&msks)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range msks {
+		fileid, err := hex.DecodeString(m.File)
+		if err != nil {
+			return num, err
+		}
+		msk, err := hex.DecodeString(m.Key)
+		if err != nil {
+			return num, err
+		}
+
+		wrapped, err := wrapKey(p, fileid, msk)
+		if err != nil {
+			return num, err
+		}
+
+		err = c.Update(bson.M{"file": m.File, "wrapped": bson.M{"$ne": true}},
+			bson.M{"$set": bson.M{
+				"key":     hex.EncodeToString(wrapped),
+				"wrapped": true,
+			}})
+		if err != nil {
+			return num, err
+		}
+		num++
+	}
+	return
+}
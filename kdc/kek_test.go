@@ -0,0 +1,82 @@
+package kdc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+var testkek = "9b2d4f1c7e8a3b6d0f5e2c1a4b7d8e9f0a3c6b5d2e1f4a7b8c9d0e3f6a5b4c7d"
+
+func init() {
+	// all the tests of kdc wrap master keys by the test KEK
+	kek, _ := hex.DecodeString(testkek)
+	KEK = StaticKEK(kek)
+}
+
+func TestKEKProviders(t *testing.T) {
+	kek, _ := hex.DecodeString(testkek)
+
+	f, err := ioutil.TempFile("", "kek")
+	if err != nil {
+		panic(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(testkek + "\n")
+	f.Close()
+
+	k, err := FileKEK(f.Name()).KEK()
+	if err != nil || !bytes.Equal(k, kek) {
+		t.Errorf("FileKEK: failed to load kek")
+	}
+
+	os.Setenv("GENARO_KDC_KEK_TEST", testkek)
+	defer os.Unsetenv("GENARO_KDC_KEK_TEST")
+	k, err = EnvKEK("GENARO_KDC_KEK_TEST").KEK()
+	if err != nil || !bytes.Equal(k, kek) {
+		t.Errorf("EnvKEK: failed to load kek")
+	}
+
+	if _, err = EnvKEK("GENARO_KDC_KEK_NONE").KEK(); err != ErrNoKEK {
+		t.Errorf("EnvKEK: missing variable is not reported")
+	}
+
+	if _, err = StaticKEK(kek[:16]).KEK(); err != ErrBadKEK {
+		t.Errorf("StaticKEK: short kek is accepted")
+	}
+}
+
+func TestWrapKey(t *testing.T) {
+	id, _ := hex.DecodeString(testid)
+	msk := []byte("0123456789abcdef")
+
+	wrapped, err := wrapKey(KEK, id, msk)
+	if err != nil {
+		panic(err)
+	}
+
+	m := &Msk{
+		File:    testid,
+		Key:     hex.EncodeToString(wrapped),
+		Wrapped: true,
+	}
+	key, err := m.masterKey()
+	if err != nil || !bytes.Equal(key, msk) {
+		t.Errorf("failed to unwrap master key")
+	}
+
+	// a wrapped key cannot be moved to another fileid
+	m.File = owner[:40]
+	if _, err = m.masterKey(); err == nil {
+		t.Errorf("wrapped key is unwrapped for another fileid")
+	}
+
+	// legacy record in plaintext
+	legacy := &Msk{File: testid, Key: hex.EncodeToString(msk)}
+	key, err = legacy.masterKey()
+	if err != nil || !bytes.Equal(key, msk) {
+		t.Errorf("failed to read legacy master key")
+	}
+}
@@ -0,0 +1,132 @@
+// KDC supplies the conversion material of multi-user SSE. A maintainer of the contract calls for the
+// deltas which convert its tokens into the ones of every writer of the contract, and the deltas are
+// encrypted to a registered storage node, so that the maintainer cannot learn the keys of other writers.
+// The storage nodes in Storages are trusted not to collude with maintainers, since a maintainer and its
+// delta together yield the multi-user scalar of the writer (see crypto/musse.go).
+// RequestG: 0x27 Norf = fileid, Enpk = ecies public key of the storage node
+// deltasResponse: 0xde Cora = fileid, Keys = pub of writer along with the encrypted delta
+
+package kdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/golang/protobuf/proto"
+	"gopkg.in/mgo.v2"
+)
+
+// Storages are the ecies public keys of storage nodes which may receive deltas. They must never
+// hand the deltas to maintainers
+var Storages [][]byte
+
+// isStorage reports whether the public key is a registered storage node
+func isStorage(pk []byte) bool {
+	for _, s := range Storages {
+		if bytes.Equal(s, pk) {
+			return true
+		}
+	}
+	return false
+}
+
+func handleRequestG(fileid, spub, enpk []byte,
+	signer Signer) ([]byte, error) {
+
+	if !isStorage(enpk) {
+		return negativeResponse([]byte("Unknown storage node"), signer)
+	}
+	epub := crypto.BytesToEciesPub(enpk, crypto.DefaultCurve)
+
+	// connect database host
+	session, err := mgo.Dial("localhost")
+	if err != nil {
+		return nil, errors.New("handleRequestG: failed to connect with local host")
+	}
+	defer session.Close()
+
+	wdb := session.DB(WilDB)
+
+	// check for permissions, the deltas would reach the entries beyond the scopes
+	if !CheckWhitelist(wdb, fileid, spub) {
+		return negativeResponse([]byte("Permission denied"), signer)
+	}
+	if scopes, err := GetScopes(wdb, fileid, spub); err != nil || scopes != nil {
+		return negativeResponse([]byte("Permission denied"), signer)
+	}
+
+	//get master key
+	mdb := session.DB(MskDB)
+	msk, err := GetMasterKey(mdb, fileid)
+	if err != nil {
+		return negativeResponse([]byte("No such fileid in kdc"), signer)
+	}
+
+	// sub keys of the reader and all the writers
+	sdb := session.DB(SaltDB)
+	subk, err := GenSubKey(sdb, msk, fileid, spub)
+	if err != nil {
+		return nil, errors.New("handleRequestG: something wrong with sub keys generation")
+	}
+	kos, err := SubKeysOf(sdb, msk, fileid)
+	if err != nil {
+		return nil, errors.New("handleRequestG: something wrong with salts search")
+	}
+
+	return deltasResponse(fileid, subk, kos, epub, signer)
+}
+
+// 0xde respond the deltas from the reader to every writer of fileid
+func deltasResponse(fileid []byte,
+	reader *SubKey,
+	writers []*KeyOwner,
+	pub *ecies.PublicKey,
+	signer Signer) ([]byte, error) {
+
+	ty := []byte{0xde}
+
+	var ras []*protobuf.ResponseAllkeys
+	for _, ko := range writers {
+		delta := crypto.MUDelta(reader.SKey, ko.SKey)
+
+		// encrypt delta by ecies pub of storage node
+		ed, err := crypto.EciesEncrypt(rand.Reader, pub, delta)
+		if err != nil {
+			return nil, errors.New("deltasResponse: failed to encrypt deltas")
+		}
+
+		ele := &protobuf.ResponseAllkeys{
+			Pub: ko.Pub,
+			Enk: ed,
+		}
+		ras = append(ras, ele)
+	}
+
+	// assemble messages
+	eds := EkeysToBytes(ras)
+	msg := make([]byte, 1+len(fileid)+len(eds))
+	copy(msg, ty)
+	copy(msg[1:], fileid)
+	copy(msg[1+len(fileid):], eds)
+
+	// sign message
+	sign, err := signer.Sign(msg)
+	if err != nil {
+		return nil, fmt.Errorf("deltasResponse: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	rep := &protobuf.Response{
+		Type: ty,
+		Cora: fileid,
+		Keys: ras,
+		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
+	}
+	return proto.Marshal(rep)
+}
@@ -0,0 +1,71 @@
+package kdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestDeltasResponse(t *testing.T) {
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	storage, _ := crypto.GenerateEciesPri(rand.Reader, crypto.DefaultCurve)
+	signer := NewLocalSigner(kpri)
+
+	msk := crypto.KeyGen()
+	reader := &SubKey{SKey: crypto.KeyDerivFunc(msk, crypto.SaltGen(), crypto.SKeyLen)}
+	writer := &KeyOwner{Pub: []byte("writer")}
+	writer.SKey = crypto.KeyDerivFunc(msk, crypto.SaltGen(), crypto.SKeyLen)
+
+	fileid := crypto.SaltGen()
+	rep, err := deltasResponse(fileid, reader, []*KeyOwner{writer}, &storage.PublicKey, signer)
+	if err != nil {
+		panic(err)
+	}
+
+	pb := &protobuf.Response{}
+	proto.Unmarshal(rep, pb)
+	msg := append(append(append([]byte{}, pb.Type...), pb.Cora...), EkeysToBytes(pb.Keys)...)
+	if !crypto.VerifySignature(msg, pb.Smsg, &kpri.PublicKey) {
+		t.Errorf("failed to verify deltas response")
+	}
+
+	delta, err := crypto.EciesDecrypt(pb.Keys[0].Enk, storage)
+	if err != nil {
+		panic(err)
+	}
+	if !bytes.Equal(delta, crypto.MUDelta(reader.SKey, writer.SKey)) {
+		t.Errorf("wrong delta")
+	}
+}
+
+func TestDeltasUnknownStorage(t *testing.T) {
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	upri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	storage, _ := crypto.GenerateEciesPri(rand.Reader, crypto.DefaultCurve)
+
+	fileid := crypto.SaltGen()
+	enpk := crypto.EciesPubToBytes(&storage.PublicKey, crypto.DefaultCurve)
+	msg := append(append([]byte{0x27}, fileid...), enpk...)
+	sign, _ := crypto.SignMessage(msg, upri)
+	req, _ := proto.Marshal(&protobuf.Request{
+		Type: []byte{0x27},
+		Norf: fileid,
+		Enpk: enpk,
+		Smsg: sign,
+	})
+
+	// deltas are never encrypted to a public key which is not a storage node
+	rep, err := ResopndToRequest(req, kpri)
+	if err != nil {
+		panic(err)
+	}
+	pb := &protobuf.Response{}
+	proto.Unmarshal(rep, pb)
+	if !bytes.Equal(pb.Type, []byte{0x00}) || string(pb.Cora) != "Unknown storage node" {
+		t.Errorf("unknown storage node should be rejected")
+	}
+}
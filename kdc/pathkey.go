@@ -0,0 +1,295 @@
+// Hierarchical path keys limit a maintainer to subtrees of the JSON document. The node key of the
+// root is derived from the master key, and the node key of "a.b" is derived from the one of "a" by
+// the segment "b", so that the holder of a node key can derive the keys of its whole subtree but
+// nothing above or beside it. The sub keys of a key path are derived from its node key.
+
+// A whitelist entry may carry the path prefixes the maintainer is allowed to access, and is encoded
+// as pub||0x00||prefix||0x00||prefix... by ScopedEntry. An entry without prefixes has full access, so
+// adding the bare entry of a scoped maintainer again lifts its scopes.
+// RequestB: 0xb2 Norf = fileid, List = requested path prefixes, which are optional
+// pathKeysResponse: 0xbc Cora = fileid, Keys = path prefix along with its encrypted node key and header
+// A maintainer with full access who requests no prefix gets the legacy expectedResponse instead.
+
+// The segments of key paths are encrypted along the path, the i-th segment by the node key of the
+// first i segments, so that the holder of a node key can recover the key paths of its subtree. The
+// header of a prefix is the encrypted segments of the prefix itself, which is made by kdc since the
+// maintainer cannot derive the node keys above its prefix.
+
+package kdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/crypto/ecies"
+	"github.com/golang/protobuf/proto"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+const (
+	// PathSeparator separates the segments of key paths
+	PathSeparator = "."
+
+	// PathKeyLen is the length of node keys
+	PathKeyLen = 32
+
+	// pubLen is the length of an uncompressed secp256k1 public key
+	pubLen = 65
+)
+
+var (
+	ErrBadPath    = errors.New("invalid path prefix")
+	ErrBadEntry   = errors.New("invalid whitelist entry")
+	ErrOutOfScope = errors.New("key path is out of scope")
+)
+
+// ScopedEntry encodes the whitelist entry of pub, which may only access the path prefixes.
+// A trailing wildcard of prefix is ignored, so "dependencies.*" is the same as "dependencies"
+func ScopedEntry(pub []byte, prefixes ...string) []byte {
+	entry := append([]byte{}, pub...)
+	for _, p := range prefixes {
+		entry = append(entry, 0x00)
+		entry = append(entry, strings.TrimSuffix(p, PathSeparator+"*")...)
+	}
+	return entry
+}
+
+// ParseEntry decodes the whitelist entry, and a nil prefixes means full access.
+// The entry must start with an uncompressed public key
+func ParseEntry(entry []byte) (pub []byte, prefixes []string, err error) {
+	if len(entry) < pubLen || entry[0] != 0x04 {
+		return nil, nil, ErrBadEntry
+	}
+	if len(entry) == pubLen {
+		return entry, nil, nil
+	}
+	if entry[pubLen] != 0x00 {
+		return nil, nil, ErrBadPath
+	}
+
+	for _, p := range bytes.Split(entry[pubLen+1:], []byte{0x00}) {
+		if !validPath(string(p)) {
+			return nil, nil, ErrBadPath
+		}
+		prefixes = append(prefixes, string(p))
+	}
+	return entry[:pubLen], prefixes, nil
+}
+
+// validPath reports whether the path has no empty segment, the empty path is the root
+func validPath(path string) bool {
+	if path == "" {
+		return true
+	}
+	for _, s := range strings.Split(path, PathSeparator) {
+		if s == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// InScope reports whether the key path is under the prefix, every path is under the root ""
+func InScope(path, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+PathSeparator)
+}
+
+// PathSegments splits the key path into segments, and the root has none
+func PathSegments(path string) []string {
+	if path == "" {
+		return nil
+	}
+	return strings.Split(path, PathSeparator)
+}
+
+// ChildKey derives the node key of the segment under the parent node
+func ChildKey(node []byte, segment string) []byte {
+	return crypto.DeriveKey(node, append([]byte("segment"), segment...), PathKeyLen)
+}
+
+// PathKey derives the node key of the key path from the master key
+func PathKey(msk []byte, path string) []byte {
+	node := crypto.DeriveKey(msk, []byte("path"), PathKeyLen)
+	for _, s := range PathSegments(path) {
+		node = ChildKey(node, s)
+	}
+	return node
+}
+
+// PathSubKey returns the sub keys of the node
+func PathSubKey(node []byte) *SubKey {
+	return &SubKey{
+		EKey: crypto.DeriveKey(node, []byte("ekey"), crypto.EKeyLen),
+		SKey: crypto.DeriveKey(node, []byte("skey"), crypto.SKeyLen),
+	}
+}
+
+// segmentData returns the associated data of the i-th segment
+func segmentData(fileid []byte, i int) []byte {
+	ad := make([]byte, len("segment")+len(fileid)+4)
+	n := copy(ad, "segment")
+	n += copy(ad[n:], fileid)
+	binary.BigEndian.PutUint32(ad[n:], uint32(i))
+	return ad
+}
+
+// EncryptSegment encrypts the i-th segment of a key path by the node key of its parent
+func EncryptSegment(parent, fileid []byte, i int, segment string) ([]byte, error) {
+	return crypto.EncryptAEAD(PathSubKey(parent).EKey, []byte(segment), segmentData(fileid, i))
+}
+
+// DecryptSegment decrypts the i-th segment of a key path by the node key of its parent
+func DecryptSegment(parent, fileid []byte, i int, esegment []byte) (string, error) {
+	s, err := crypto.DecryptAEAD(PathSubKey(parent).EKey, esegment, segmentData(fileid, i))
+	if err != nil {
+		return "", ErrOutOfScope
+	}
+	return string(s), nil
+}
+
+// PathHeader encrypts the segments of the prefix along the path
+func PathHeader(msk, fileid []byte, prefix string) (header [][]byte, err error) {
+	path := ""
+	for i, s := range PathSegments(prefix) {
+		es, err := EncryptSegment(PathKey(msk, path), fileid, i, s)
+		if err != nil {
+			return nil, err
+		}
+		header = append(header, es)
+
+		if i > 0 {
+			path += PathSeparator
+		}
+		path += s
+	}
+	return
+}
+
+// grantPrefixes returns the requested prefixes which are in the scopes, or all the scopes if none
+// is requested. A nil scopes has full access, which is the root
+func grantPrefixes(scopes []string, requested [][]byte) ([]string, error) {
+	if scopes == nil {
+		scopes = []string{""}
+	}
+	if len(requested) == 0 {
+		return scopes, nil
+	}
+
+	var granted []string
+	for _, r := range requested {
+		prefix := strings.TrimSuffix(string(r), PathSeparator+"*")
+		if !validPath(prefix) {
+			return nil, ErrBadPath
+		}
+		for _, s := range scopes {
+			if InScope(prefix, s) {
+				granted = append(granted, prefix)
+				break
+			}
+		}
+	}
+	if len(granted) == 0 {
+		return nil, ErrNoAccess
+	}
+	return granted, nil
+}
+
+// SetScopes limits the public key in whitelist to the path prefixes, and nil prefixes restore full access
+func SetScopes(c *mgo.Collection, fileid, pub []byte, prefixes []string) error {
+	file := hex.EncodeToString(fileid)
+	sn := hex.EncodeToString(pub)
+
+	op := "$set"
+	if prefixes == nil {
+		op = "$unset"
+	}
+	return c.Update(bson.M{"file": file},
+		bson.M{op: bson.M{
+			"scopes." + sn: prefixes,
+		}, "$inc": bson.M{
+			"version": 1,
+		}})
+}
+
+// GetScopes returns the path prefixes the public key may access, and nil means full access
+func GetScopes(d *mgo.Database, fileid, pub []byte) ([]string, error) {
+	wl, err := GetWhitelist(d, fileid)
+	if err != nil {
+		return nil, err
+	}
+
+	// the owner always has full access
+	sn := hex.EncodeToString(pub)
+	if wl.Owner == sn {
+		return nil, nil
+	}
+	return wl.Scopes[sn], nil
+}
+
+// 0xbc respond the node keys of the prefixes along with their headers
+func pathKeysResponse(fileid, msk []byte,
+	prefixes []string,
+	pub *ecies.PublicKey,
+	signer Signer) ([]byte, error) {
+
+	ty := []byte{0xbc}
+
+	var ras []*protobuf.ResponseAllkeys
+	for _, prefix := range prefixes {
+		header, err := PathHeader(msk, fileid, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("pathKeysResponse: failed to encrypt header with error: %s", err.Error())
+		}
+
+		// m = node||len||segment||len||segment...
+		m := PathKey(msk, prefix)
+		for _, es := range header {
+			var size [4]byte
+			binary.BigEndian.PutUint32(size[:], uint32(len(es)))
+			m = append(append(m, size[:]...), es...)
+		}
+
+		// encrypt node key and header by client's ecies public key
+		ek, err := crypto.EciesEncrypt(rand.Reader, pub, m)
+		if err != nil {
+			return nil, errors.New("pathKeysResponse: failed to encrypt path keys")
+		}
+
+		ele := &protobuf.ResponseAllkeys{
+			Pub: []byte(prefix),
+			Enk: ek,
+		}
+		ras = append(ras, ele)
+	}
+
+	// assemble messages
+	eks := EkeysToBytes(ras)
+	msg := make([]byte, 1+len(fileid)+len(eks))
+	copy(msg, ty)
+	copy(msg[1:], fileid)
+	copy(msg[1+len(fileid):], eks)
+
+	// sign message
+	sign, err := signer.Sign(msg)
+	if err != nil {
+		return nil, fmt.Errorf("pathKeysResponse: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	rep := &protobuf.Response{
+		Type: ty,
+		Cora: fileid,
+		Keys: ras,
+		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
+	}
+	return proto.Marshal(rep)
+}
@@ -0,0 +1,109 @@
+package kdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestScopedEntry(t *testing.T) {
+	upri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	pub := crypto.EcdsaPubToBytes(&upri.PublicKey, crypto.DefaultCurve)
+
+	// a plain public key has full access
+	p, prefixes, err := ParseEntry(pub)
+	if err != nil || !bytes.Equal(p, pub) || prefixes != nil {
+		t.Errorf("failed to parse plain entry")
+	}
+
+	p, prefixes, err = ParseEntry(ScopedEntry(pub, "dependencies.*", "scripts.build"))
+	if err != nil || !bytes.Equal(p, pub) {
+		t.Fatalf("failed to parse scoped entry")
+	}
+	if len(prefixes) != 2 || prefixes[0] != "dependencies" || prefixes[1] != "scripts.build" {
+		t.Errorf("wrong prefixes %q", prefixes)
+	}
+
+	if _, _, err = ParseEntry(ScopedEntry(pub, "a..b")); err != ErrBadPath {
+		t.Errorf("empty segment should be rejected")
+	}
+
+	// an entry must start with an uncompressed public key
+	for _, bad := range [][]byte{nil, pub[:pubLen-1], append([]byte{0x02}, pub[1:]...)} {
+		if _, _, err = ParseEntry(bad); err != ErrBadEntry {
+			t.Errorf("entry of %d bytes should be rejected", len(bad))
+		}
+	}
+}
+
+func TestGrantPrefixes(t *testing.T) {
+	scopes := []string{"dependencies", "scripts.build"}
+
+	granted, err := grantPrefixes(scopes, nil)
+	if err != nil || len(granted) != 2 {
+		t.Errorf("all the scopes should be granted")
+	}
+
+	granted, err = grantPrefixes(scopes, [][]byte{[]byte("dependencies.lodash"), []byte("scripts")})
+	if err != nil || len(granted) != 1 || granted[0] != "dependencies.lodash" {
+		t.Errorf("only the prefixes in scopes should be granted, got %q", granted)
+	}
+
+	if _, err = grantPrefixes(scopes, [][]byte{[]byte("")}); err != ErrNoAccess {
+		t.Errorf("the root should not be granted to a scoped maintainer")
+	}
+
+	// full access
+	granted, err = grantPrefixes(nil, nil)
+	if err != nil || len(granted) != 1 || granted[0] != "" {
+		t.Errorf("the root should be granted for full access")
+	}
+}
+
+func TestPathKeysResponse(t *testing.T) {
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	upri, _ := crypto.GenerateEciesPri(rand.Reader, crypto.DefaultCurve)
+	signer := NewLocalSigner(kpri)
+
+	msk := crypto.KeyGen()
+	fileid := crypto.SaltGen()
+	rep, err := pathKeysResponse(fileid, msk, []string{"dependencies.lodash"}, &upri.PublicKey, signer)
+	if err != nil {
+		panic(err)
+	}
+
+	pb := &protobuf.Response{}
+	proto.Unmarshal(rep, pb)
+	msg := append(append(append([]byte{}, pb.Type...), pb.Cora...), EkeysToBytes(pb.Keys)...)
+	if !crypto.VerifySignature(msg, pb.Smsg, &kpri.PublicKey) {
+		t.Errorf("failed to verify path keys response")
+	}
+
+	m, err := crypto.EciesDecrypt(pb.Keys[0].Enk, upri)
+	if err != nil {
+		panic(err)
+	}
+	node := m[:PathKeyLen]
+	if !bytes.Equal(node, PathKey(msk, "dependencies.lodash")) {
+		t.Errorf("wrong node key")
+	}
+
+	// the node key of a child is derived from its parent
+	if !bytes.Equal(ChildKey(PathKey(msk, "dependencies"), "lodash"), node) {
+		t.Errorf("node keys should be hierarchical")
+	}
+
+	// the header decrypts along the path
+	header, _ := PathHeader(msk, fileid, "dependencies.lodash")
+	s, err := DecryptSegment(PathKey(msk, "dependencies"), fileid, 1, header[1])
+	if err != nil || s != "lodash" {
+		t.Errorf("failed to decrypt header")
+	}
+	if _, err = DecryptSegment(PathKey(msk, "scripts"), fileid, 1, header[1]); err != ErrOutOfScope {
+		t.Errorf("other node key should not decrypt the header")
+	}
+}
@@ -0,0 +1,481 @@
+// Attribute-based access lets the owner grant the keys of a contract by a boolean policy over user
+// attributes, such as "department=audit AND clearance>=2", in addition to the flat whitelist.
+// The attributes are issued to a public key as credentials signed by an attribute issuer, and the
+// requester attaches them to RequestB. The credentials are bound to the public key of the holder, so
+// they are not signed by the requester again.
+// RequestH: 0x38 Norf = fileid, List = policy, and an empty policy removes the policy
+// The policy is responded by positiveResponse, and only the owner can set it.
+
+// The grammar of policy, where the keywords are case-insensitive:
+// expr := term { OR term }
+// term := factor { AND factor }
+// factor := ( expr ) | name op value
+// op := = | >= | <= | > | <
+// The ordering operators compare integers only, and a condition on a missing attribute is false.
+// A value containing spaces or operators can be double quoted.
+// The requester chooses which credentials to attach, so the policy must be monotone: more
+// credentials never turn it from true to false. NOT and != are rejected, since withholding a
+// credential would satisfy "NOT revoked=true", and any other value would satisfy "role!=guest".
+
+package kdc
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Issuers are the ecdsa public keys of attribute issuers trusted by kdc
+var Issuers [][]byte
+
+var (
+	ErrBadPolicy     = errors.New("invalid access policy")
+	ErrBadCredential = errors.New("invalid attribute credential")
+)
+
+// Attributes of a public key, and a name may have several values from different credentials
+type Attributes map[string][]string
+
+type policyNode interface {
+	eval(attrs Attributes) bool
+}
+
+type andNode []policyNode
+
+func (n andNode) eval(attrs Attributes) bool {
+	for _, c := range n {
+		if !c.eval(attrs) {
+			return false
+		}
+	}
+	return true
+}
+
+type orNode []policyNode
+
+func (n orNode) eval(attrs Attributes) bool {
+	for _, c := range n {
+		if c.eval(attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+type condNode struct {
+	name, op, value string
+}
+
+func (n condNode) eval(attrs Attributes) bool {
+	for _, v := range attrs[n.name] {
+		if compare(v, n.op, n.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// compare reports whether "a op b" holds
+func compare(a, op, b string) bool {
+	if op == "=" {
+		return a == b
+	}
+
+	x, err := strconv.ParseInt(a, 10, 64)
+	if err != nil {
+		return false
+	}
+	y, err := strconv.ParseInt(b, 10, 64)
+	if err != nil {
+		return false
+	}
+	switch op {
+	case ">=":
+		return x >= y
+	case "<=":
+		return x <= y
+	case ">":
+		return x > y
+	case "<":
+		return x < y
+	}
+	return false
+}
+
+// Policy is a parsed access policy
+type Policy struct {
+	src  string
+	root policyNode
+}
+
+// String returns the source of policy
+func (p *Policy) String() string {
+	return p.src
+}
+
+// Evaluate reports whether the attributes satisfy the policy
+func (p *Policy) Evaluate(attrs Attributes) bool {
+	return p.root.eval(attrs)
+}
+
+// tokenize splits the policy into words, quoted values, operators and parentheses
+func tokenize(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := strings.IndexByte(src[i+1:], '"')
+			if j < 0 {
+				return nil, ErrBadPolicy
+			}
+			tokens = append(tokens, src[i:i+j+2])
+			i += j + 2
+		case strings.IndexByte("=!<>", c) >= 0:
+			if i+1 < len(src) && src[i+1] == '=' {
+				tokens = append(tokens, src[i:i+2])
+				i += 2
+				continue
+			}
+			if c == '!' {
+				return nil, ErrBadPolicy
+			}
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			j := i
+			for j < len(src) && strings.IndexByte(" \t\n()\"=!<>", src[j]) < 0 {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type policyParser struct {
+	tokens []string
+	pos    int
+}
+
+func (pp *policyParser) peek() string {
+	if pp.pos < len(pp.tokens) {
+		return pp.tokens[pp.pos]
+	}
+	return ""
+}
+
+func (pp *policyParser) next() string {
+	t := pp.peek()
+	pp.pos++
+	return t
+}
+
+func (pp *policyParser) expr() (policyNode, error) {
+	var or orNode
+	for {
+		t, err := pp.term()
+		if err != nil {
+			return nil, err
+		}
+		or = append(or, t)
+		if !strings.EqualFold(pp.peek(), "OR") {
+			break
+		}
+		pp.next()
+	}
+	if len(or) == 1 {
+		return or[0], nil
+	}
+	return or, nil
+}
+
+func (pp *policyParser) term() (policyNode, error) {
+	var and andNode
+	for {
+		f, err := pp.factor()
+		if err != nil {
+			return nil, err
+		}
+		and = append(and, f)
+		if !strings.EqualFold(pp.peek(), "AND") {
+			break
+		}
+		pp.next()
+	}
+	if len(and) == 1 {
+		return and[0], nil
+	}
+	return and, nil
+}
+
+func (pp *policyParser) factor() (policyNode, error) {
+	t := pp.next()
+	if t == "(" {
+		e, err := pp.expr()
+		if err != nil {
+			return nil, err
+		}
+		if pp.next() != ")" {
+			return nil, ErrBadPolicy
+		}
+		return e, nil
+	}
+
+	name, op, value := t, pp.next(), pp.next()
+	if !isWord(name) || !isOperator(op) || (!isWord(value) && !isQuoted(value)) {
+		return nil, ErrBadPolicy
+	}
+	return condNode{name, op, strings.Trim(value, "\"")}, nil
+}
+
+func isOperator(t string) bool {
+	switch t {
+	case "=", ">=", "<=", ">", "<":
+		return true
+	}
+	return false
+}
+
+func isQuoted(t string) bool {
+	return len(t) >= 2 && t[0] == '"' && t[len(t)-1] == '"'
+}
+
+func isWord(t string) bool {
+	if t == "" || t == "(" || t == ")" || isOperator(t) || isQuoted(t) {
+		return false
+	}
+	switch strings.ToUpper(t) {
+	case "AND", "OR", "NOT":
+		return false
+	}
+	return true
+}
+
+// ParsePolicy parses the source of access policy
+func ParsePolicy(src string) (*Policy, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+
+	pp := &policyParser{tokens: tokens}
+	root, err := pp.expr()
+	if err != nil {
+		return nil, err
+	}
+	if pp.pos != len(tokens) {
+		return nil, ErrBadPolicy
+	}
+	return &Policy{src: src, root: root}, nil
+}
+
+// CredentialMsg assembles the signed message of a credential:
+// 0xe2||kid||pub||name||0x00||value||0x00...||expiry
+func CredentialMsg(kid, pub []byte, attrs []*protobuf.CredentialAttribute, expiry int64) []byte {
+	msg := append([]byte{0xe2}, kid...)
+	msg = append(msg, pub...)
+	for _, a := range attrs {
+		msg = append(append(msg, a.GetName()...), 0x00)
+		msg = append(append(msg, a.GetValue()...), 0x00)
+	}
+
+	var stamp [8]byte
+	binary.BigEndian.PutUint64(stamp[:], uint64(expiry))
+	return append(msg, stamp[:]...)
+}
+
+// IssueCredential returns a credential of the attributes for the public key signed by the issuer
+func IssueCredential(signer Signer, pub []byte, attrs map[string]string, expiry int64) ([]byte, error) {
+	var names []string
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var as []*protobuf.CredentialAttribute
+	for _, name := range names {
+		if strings.IndexByte(name, 0x00) >= 0 || strings.IndexByte(attrs[name], 0x00) >= 0 {
+			return nil, ErrBadCredential
+		}
+		as = append(as, &protobuf.CredentialAttribute{
+			Name:  proto.String(name),
+			Value: proto.String(attrs[name]),
+		})
+	}
+
+	kid := crypto.KeyID(signer.Public())
+	sign, err := signer.Sign(CredentialMsg(kid, pub, as, expiry))
+	if err != nil {
+		return nil, fmt.Errorf("IssueCredential: failed to sign message with error: %s", err.Error())
+	}
+
+	return proto.Marshal(&protobuf.Credential{
+		Pub:    pub,
+		Attrs:  as,
+		Expiry: proto.Int64(expiry),
+		Kid:    kid,
+		Smsg:   sign,
+	})
+}
+
+// issuer returns the issuer of the key identifier among the trusted ones
+func issuer(issuers [][]byte, kid []byte) *ecdsa.PublicKey {
+	for _, pk := range issuers {
+		pub := crypto.BytesToEcdsaPub(pk, crypto.DefaultCurve)
+		if pub != nil && pub.X != nil && string(crypto.KeyID(pub)) == string(kid) {
+			return pub
+		}
+	}
+	return nil
+}
+
+// VerifyCredential checks the credential of the public key, and returns its attributes
+func VerifyCredential(buf, pub []byte) (Attributes, error) {
+	return VerifyCredentialBy(Issuers, buf, pub)
+}
+
+// VerifyCredentialBy checks the credential of the public key against the given issuers,
+// and returns its attributes
+func VerifyCredentialBy(issuers [][]byte, buf, pub []byte) (Attributes, error) {
+	cred := &protobuf.Credential{}
+	err := proto.Unmarshal(buf, cred)
+	if err != nil {
+		return nil, ErrBadCredential
+	}
+
+	if string(cred.Pub) != string(pub) || cred.GetExpiry() < time.Now().Unix() {
+		return nil, ErrBadCredential
+	}
+
+	is := issuer(issuers, cred.Kid)
+	if is == nil {
+		return nil, ErrBadCredential
+	}
+	if !crypto.VerifySignature(CredentialMsg(cred.Kid, cred.Pub, cred.Attrs, cred.GetExpiry()), cred.Smsg, is) {
+		return nil, ErrBadCredential
+	}
+
+	attrs := make(Attributes)
+	for _, a := range cred.Attrs {
+		if strings.IndexByte(a.GetName(), 0x00) >= 0 || strings.IndexByte(a.GetValue(), 0x00) >= 0 {
+			return nil, ErrBadCredential
+		}
+		attrs[a.GetName()] = append(attrs[a.GetName()], a.GetValue())
+	}
+	return attrs, nil
+}
+
+// credentialAttrs merges the attributes of the valid credentials, and ignores the others
+func credentialAttrs(issuers, creds [][]byte, pub []byte) Attributes {
+	attrs := make(Attributes)
+	for _, buf := range creds {
+		as, err := VerifyCredentialBy(issuers, buf, pub)
+		if err != nil {
+			continue
+		}
+		for name, values := range as {
+			attrs[name] = append(attrs[name], values...)
+		}
+	}
+	return attrs
+}
+
+// SetPolicy saves the access policy of the file, and an empty policy removes it
+func SetPolicy(c *mgo.Collection, fileid []byte, policy string) error {
+	file := hex.EncodeToString(fileid)
+
+	return c.Update(bson.M{"file": file},
+		bson.M{"$set": bson.M{
+			"policy": policy,
+		}, "$inc": bson.M{
+			"version": 1,
+		}})
+}
+
+// EvaluatePolicy checks whether the attributes in the credentials of the public key, issued by
+// the given issuers, satisfy the policy. An empty policy admits nobody
+func EvaluatePolicy(policy string, issuers, creds [][]byte, pub []byte) bool {
+	if policy == "" || len(creds) == 0 {
+		return false
+	}
+
+	p, err := ParsePolicy(policy)
+	if err != nil {
+		return false
+	}
+	return p.Evaluate(credentialAttrs(issuers, creds, pub))
+}
+
+// CheckPolicy checks whether the attributes in the credentials of the public key satisfy
+// the access policy of the file. A file without policy admits nobody by policy
+func CheckPolicy(d *mgo.Database, fileid, pub []byte, creds [][]byte) bool {
+	if len(creds) == 0 {
+		return false
+	}
+
+	wl, err := GetWhitelist(d, fileid)
+	if err != nil {
+		return false
+	}
+	return EvaluatePolicy(wl.Policy, Issuers, creds, pub)
+}
+
+func handleRequestH(fileid, pub []byte,
+	list [][]byte,
+	signer Signer) ([]byte, error) {
+
+	if len(list) != 1 {
+		return negativeResponse([]byte("Invalid policy"), signer)
+	}
+	policy := string(list[0])
+	if policy != "" {
+		if _, err := ParsePolicy(policy); err != nil {
+			return negativeResponse([]byte("Invalid policy"), signer)
+		}
+	}
+
+	// connect database host
+	session, err := mgo.Dial("localhost")
+	if err != nil {
+		return nil, errors.New("handleRequestH: failed to connect with local host")
+	}
+	defer session.Close()
+
+	c := session.DB(WilDB).C(WilCol)
+
+	// check for permissions
+	result := new(WhiteList)
+	spub := hex.EncodeToString(pub)
+	id := hex.EncodeToString(fileid)
+	err = c.Find(bson.M{"file": id, "owner": spub}).One(&result)
+	if err != nil {
+		// only owner can set the policy
+		return negativeResponse([]byte("Permission denied"), signer)
+	}
+
+	err = SetPolicy(c, fileid, policy)
+	if err != nil {
+		return nil, fmt.Errorf("handleRequestH: failed to set policy with error: %s", err.Error())
+	}
+
+	return positiveResponse([]byte("The policy has been set successfully"), signer)
+}
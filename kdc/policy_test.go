@@ -0,0 +1,137 @@
+package kdc
+
+import (
+	"crypto/rand"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestParsePolicy(t *testing.T) {
+	attrs := Attributes{
+		"department": {"audit"},
+		"clearance":  {"2"},
+		"role":       {"reviewer", "lead"},
+		"site":       {"new york"},
+	}
+
+	cases := []struct {
+		policy string
+		expect bool
+	}{
+		{"department=audit AND clearance>=2", true},
+		{"department=audit AND clearance>2", false},
+		{"department=legal OR clearance<3", true},
+		{"department = audit and (role=lead or clearance>=5)", true},
+		{`site="new york"`, true},
+		{"missing=1 OR role=lead", true},
+		{"department>=1", false},
+	}
+	for _, c := range cases {
+		p, err := ParsePolicy(c.policy)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", c.policy, err)
+			continue
+		}
+		if p.Evaluate(attrs) != c.expect {
+			t.Errorf("%q evaluated to %v", c.policy, !c.expect)
+		}
+	}
+
+	// non-monotone policies are rejected
+	for _, bad := range []string{"NOT department=audit", "NOT (department=legal OR missing=1)", "department!=legal", "not revoked=true"} {
+		if _, err := ParsePolicy(bad); err != ErrBadPolicy {
+			t.Errorf("%q should be rejected as non-monotone", bad)
+		}
+	}
+
+	for _, bad := range []string{"", "department", "department=", "a=1 AND", "(a=1", "a=1)", "a!1", `a="1`, "a=1 b=2", "AND=1"} {
+		if _, err := ParsePolicy(bad); err != ErrBadPolicy {
+			t.Errorf("%q should be invalid", bad)
+		}
+	}
+}
+
+func TestCredential(t *testing.T) {
+	ipri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	upri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	signer := NewLocalSigner(ipri)
+	pub := crypto.EcdsaPubToBytes(&upri.PublicKey, crypto.DefaultCurve)
+
+	expiry := time.Now().Add(time.Hour).Unix()
+	cred, err := IssueCredential(signer, pub, map[string]string{"department": "audit", "clearance": "2"}, expiry)
+	if err != nil {
+		panic(err)
+	}
+
+	// the issuer is not trusted yet
+	if _, err = VerifyCredential(cred, pub); err != ErrBadCredential {
+		t.Errorf("credential of untrusted issuer should be rejected")
+	}
+
+	Issuers = [][]byte{crypto.EcdsaPubToBytes(&ipri.PublicKey, crypto.DefaultCurve)}
+	defer func() { Issuers = nil }()
+
+	attrs, err := VerifyCredential(cred, pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ := ParsePolicy("department=audit AND clearance>=2")
+	if !p.Evaluate(attrs) {
+		t.Errorf("attributes should satisfy the policy")
+	}
+
+	// the credential is bound to the holder
+	if _, err = VerifyCredential(cred, []byte("someone else")); err != ErrBadCredential {
+		t.Errorf("credential of another holder should be rejected")
+	}
+
+	// tampered attributes
+	pb := &protobuf.Credential{}
+	proto.Unmarshal(cred, pb)
+	pb.Attrs[0].Value = proto.String("9")
+	tampered, _ := proto.Marshal(pb)
+	if _, err = VerifyCredential(tampered, pub); err != ErrBadCredential {
+		t.Errorf("tampered credential should be rejected")
+	}
+
+	// expired
+	old, _ := IssueCredential(signer, pub, map[string]string{"department": "audit"}, time.Now().Add(-time.Hour).Unix())
+	if _, err = VerifyCredential(old, pub); err != ErrBadCredential {
+		t.Errorf("expired credential should be rejected")
+	}
+
+	// invalid credentials are ignored when merged
+	attrs = credentialAttrs(Issuers, [][]byte{old, cred, []byte("junk")}, pub)
+	if len(attrs["department"]) != 1 || attrs["clearance"][0] != "2" {
+		t.Errorf("wrong merged attributes %v", attrs)
+	}
+
+	// withholding a credential never satisfies a policy
+	dept, _ := IssueCredential(signer, pub, map[string]string{"department": "audit"}, expiry)
+	clear, _ := IssueCredential(signer, pub, map[string]string{"clearance": "3"}, expiry)
+	p, _ = ParsePolicy("department=audit AND clearance>=3")
+	for _, creds := range [][][]byte{nil, {dept}, {clear}} {
+		if p.Evaluate(credentialAttrs(Issuers, creds, pub)) {
+			t.Errorf("policy is satisfied with %d of 2 credentials", len(creds))
+		}
+	}
+	if !p.Evaluate(credentialAttrs(Issuers, [][]byte{dept, clear}, pub)) {
+		t.Errorf("policy should be satisfied with both credentials")
+	}
+
+	// the issuers are given by the caller, such as a snapshot
+	both := [][]byte{dept, clear}
+	if !EvaluatePolicy(p.String(), Issuers, both, pub) {
+		t.Errorf("policy should be satisfied by the trusted issuers")
+	}
+	if EvaluatePolicy(p.String(), nil, both, pub) {
+		t.Errorf("policy is satisfied without trusted issuers")
+	}
+	if EvaluatePolicy("", Issuers, both, pub) {
+		t.Errorf("empty policy admits the credentials")
+	}
+}
@@ -1,8 +1,14 @@
-// There are four kinds of responses
+// There are eight kinds of responses
 // negativeResponse: 0x00 kdc rejects the request of user
-// positiveResponse: 0xcd kdc responds the executing state for RequestC
+// positiveResponse: 0xcd kdc responds the executing state for RequestC or RequestH
 // expectedResponse: 0xab kdc returns the the corresponding keys for RequestA or RequestB
 // allKeysResponse:  0xef kdc returns all keys for RequestE
+// snapshotResponse: 0xfa kdc returns a signed whitelist snapshot for RequestF
+// deltasResponse:   0xde kdc returns the multi-user SSE deltas for RequestG
+// pathKeysResponse: 0xbc kdc returns the path keys of the granted subtrees for RequestB
+// Each response carries the identifier of the signing key, so that clients can choose the
+// public key from their trust store when kdc rotates its key. The identifier is not signed,
+// since a forged one only selects a key which fails to verify the signature.
 // Note that the RequestD has no need to respond
 
 package kdc
@@ -11,6 +17,7 @@ import (
 	"bytes"
 	"crypto/ecdsa"
 	"crypto/rand"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -26,14 +33,25 @@ import (
 // ResopndToRequest is a high level encapsulation for kdc functions
 // if response == err == nil, it means that  there is no need to respond
 func ResopndToRequest(request []byte, pri *ecdsa.PrivateKey) (response []byte, err error) {
+	return RespondWithSigner(request, NewLocalSigner(pri))
+}
+
+// RespondWithSigner responds to the request like ResopndToRequest, and the response
+// is signed by the signer which may keep the private key of kdc out of process
+func RespondWithSigner(request []byte, signer Signer) (response []byte, err error) {
 	req := &protobuf.Request{}
 	err = proto.Unmarshal(request, req)
 	if err != nil {
 		return nil, err
 	}
 
+	// reject all requests until kdc is unsealed
+	if checkSealed() {
+		return negativeResponse([]byte("KDC is sealed"), signer)
+	}
+
 	// verify request buffer
-	list := bytes.Join(req.List, []byte(""))
+	list := ListToBytes(req.List)
 	msg := make([]byte, 1+len(req.Norf)+len(req.Snon)+len(req.Enpk)+len(list))
 	copy(msg, req.Type)
 	copy(msg[1:], req.Norf)
@@ -41,26 +59,26 @@ func ResopndToRequest(request []byte, pri *ecdsa.PrivateKey) (response []byte, e
 	copy(msg[1+len(req.Norf)+len(req.Snon):], req.Enpk)
 	copy(msg[1+len(req.Norf)+len(req.Snon)+len(req.Enpk):], list)
 	if !crypto.VerifySignNoPub(msg, req.Smsg) {
-		return negativeResponse([]byte("Request has been tampered"), pri)
+		return negativeResponse([]byte("Request has been tampered"), signer)
 	}
 
 	// handle RequestA
 	if bytes.Equal(req.Type, []byte{0xa1}) {
 		pub := crypto.BytesToEciesPub(req.Enpk, crypto.DefaultCurve)
-		return handleRequestA(msg, req, pub, pri)
+		return handleRequestA(msg, req, pub, signer)
 	}
 
 	// handle RequestB
 	if bytes.Equal(req.Type, []byte{0xb2}) {
 		spub, _ := crypto.PubFromSign(msg, req.Smsg)
 		epub := crypto.BytesToEciesPub(req.Enpk, crypto.DefaultCurve)
-		return handleRequestB(req.Norf, spub, epub, pri)
+		return handleRequestB(req.Norf, spub, req.List, req.Cred, epub, signer)
 	}
 
 	// handle RequestC
 	if bytes.Equal(req.Type, []byte{0xc3}) {
 		spub, _ := crypto.PubFromSign(msg, req.Smsg)
-		return handleRequestC(req.Norf, spub, req.List, pri)
+		return handleRequestC(req.Norf, spub, req.List, signer)
 	}
 
 	// handle RequestD
@@ -74,7 +92,25 @@ func ResopndToRequest(request []byte, pri *ecdsa.PrivateKey) (response []byte, e
 	if bytes.Equal(req.Type, []byte{0xe5}) {
 		spub, _ := crypto.PubFromSign(msg, req.Smsg)
 		epub := crypto.BytesToEciesPub(req.Enpk, crypto.DefaultCurve)
-		return handleRequestE(req.Norf, spub, epub, pri)
+		return handleRequestE(req.Norf, spub, epub, signer)
+	}
+
+	// handle RequestF
+	if bytes.Equal(req.Type, []byte{0xf6}) {
+		spub, _ := crypto.PubFromSign(msg, req.Smsg)
+		return handleRequestF(req.Norf, spub, signer)
+	}
+
+	// handle RequestG
+	if bytes.Equal(req.Type, []byte{0x27}) {
+		spub, _ := crypto.PubFromSign(msg, req.Smsg)
+		return handleRequestG(req.Norf, spub, req.Enpk, signer)
+	}
+
+	// handle RequestH
+	if bytes.Equal(req.Type, []byte{0x38}) {
+		spub, _ := crypto.PubFromSign(msg, req.Smsg)
+		return handleRequestH(req.Norf, spub, req.List, signer)
 	}
 
 	return nil, nil
@@ -83,16 +119,21 @@ func ResopndToRequest(request []byte, pri *ecdsa.PrivateKey) (response []byte, e
 func handleRequestA(msg []byte,
 	req *protobuf.Request,
 	pub *ecies.PublicKey,
-	pri *ecdsa.PrivateKey) ([]byte, error) {
+	signer Signer) ([]byte, error) {
 
 	//verify whether  the two public keys from Snon and Smsg are the same
 	pub1, err := crypto.PubFromSign(req.Norf, req.Snon)
 	if err != nil {
-		return negativeResponse([]byte("Bad signature of nonce"), pri)
+		return negativeResponse([]byte("Bad signature of nonce"), signer)
 	}
 	pub2, _ := crypto.PubFromSign(msg, req.Smsg)
 	if !bytes.Equal(pub1, pub2) {
-		return negativeResponse([]byte("Illegal request"), pri)
+		return negativeResponse([]byte("Illegal request"), signer)
+	}
+
+	// check the whitelist before anything is stored, so that a bad entry leaves no contract behind
+	if _, _, err = parseList(req.List); err != nil {
+		return negativeResponse([]byte("Bad whitelist entry"), signer)
 	}
 
 	// connect database host
@@ -104,6 +145,7 @@ func handleRequestA(msg []byte,
 
 	mdb := session.DB(MskDB)
 	sdb := session.DB(SaltDB)
+	wdb := session.DB(WilDB)
 
 	// It must be a protogenous request from a contract builder
 	// generate fileid
@@ -111,6 +153,14 @@ func handleRequestA(msg []byte,
 	msk, _ := GetMasterKey(mdb, fileid[:])
 	if msk != nil {
 		// It is a repeated request and associated data has been stored.
+		// save whitelist if the former request failed before saving it
+		if _, err = GetWhitelist(wdb, fileid[:]); err == mgo.ErrNotFound {
+			err = SaveWhitelist(wdb, fileid[:], pub1, req.List)
+			if err != nil {
+				return nil, errors.New("handleRequestA: something wrong with whitelist saving")
+			}
+		}
+
 		// generate sub keys
 		subk, err := GenSubKey(sdb, msk, fileid[:], pub1)
 		if err != nil {
@@ -118,7 +168,7 @@ func handleRequestA(msg []byte,
 		}
 
 		// return an expected response
-		return expectedResponse(fileid[:], subk, pub, pri)
+		return expectedResponse(fileid[:], subk, pub, signer)
 	}
 
 	// It is a new request
@@ -134,19 +184,19 @@ func handleRequestA(msg []byte,
 	}
 
 	// save whitelist
-	wdb := session.DB(WilDB)
 	err = SaveWhitelist(wdb, fileid[:], pub1, req.List)
 	if err != nil {
 		return nil, errors.New("handleRequestA: something wrong with whitelist saving")
 	}
 
 	// return an expected response
-	return expectedResponse(fileid[:], subk, pub, pri)
+	return expectedResponse(fileid[:], subk, pub, signer)
 }
 
 func handleRequestB(fileid, spub []byte,
+	list, creds [][]byte,
 	epub *ecies.PublicKey,
-	kpri *ecdsa.PrivateKey) ([]byte, error) {
+	signer Signer) ([]byte, error) {
 
 	// connect database host
 	session, err := mgo.Dial("localhost")
@@ -157,16 +207,29 @@ func handleRequestB(fileid, spub []byte,
 
 	wdb := session.DB(WilDB)
 
-	// check for permissions
-	if !CheckWhitelist(wdb, fileid, spub) {
-		return negativeResponse([]byte("Permission denied"), kpri)
+	// check for permissions by whitelist or by access policy
+	if !CheckWhitelist(wdb, fileid, spub) && !CheckPolicy(wdb, fileid, spub, creds) {
+		return negativeResponse([]byte("Permission denied"), signer)
 	}
 
 	//get master key
 	mdb := session.DB(MskDB)
 	msk, err := GetMasterKey(mdb, fileid)
 	if err != nil {
-		return negativeResponse([]byte("No such fileid in kdc"), kpri)
+		return negativeResponse([]byte("No such fileid in kdc"), signer)
+	}
+
+	// a maintainer limited to subtrees only gets their path keys
+	scopes, err := GetScopes(wdb, fileid, spub)
+	if err != nil {
+		return nil, fmt.Errorf("handleRequestB: failed to get scopes with error: %s", err.Error())
+	}
+	if scopes != nil || len(list) > 0 {
+		prefixes, err := grantPrefixes(scopes, list)
+		if err != nil {
+			return negativeResponse([]byte("Permission denied"), signer)
+		}
+		return pathKeysResponse(fileid, msk.Key, prefixes, epub, signer)
 	}
 
 	//generate sub keys
@@ -177,12 +240,12 @@ func handleRequestB(fileid, spub []byte,
 	}
 
 	// return an expected response
-	return expectedResponse(fileid, subk, epub, kpri)
+	return expectedResponse(fileid, subk, epub, signer)
 }
 
 func handleRequestC(fileid, pub []byte,
 	list [][]byte,
-	kpri *ecdsa.PrivateKey) ([]byte, error) {
+	signer Signer) ([]byte, error) {
 
 	// connect database host
 	session, err := mgo.Dial("localhost")
@@ -200,27 +263,36 @@ func handleRequestC(fileid, pub []byte,
 	err = c.Find(bson.M{"file": id, "owner": spub}).One(&result)
 	if err != nil {
 		// only owner can update the whitelist
-		return negativeResponse([]byte("Permission denied"), kpri)
+		return negativeResponse([]byte("Permission denied"), signer)
 	}
 
 	counter := 0
-	for _, pub := range list {
-		err := UpdateWhitelist(c, fileid, pub)
+	for _, entry := range list {
+		pub, prefixes, err := ParseEntry(entry)
+		if err != nil {
+			return negativeResponse([]byte("Invalid whitelist entry"), signer)
+		}
+
+		err = UpdateWhitelist(c, fileid, pub)
 		if err == nil {
 			counter++
-			continue
+		} else if err != ErrPubExist {
+			return nil, err
 		}
-		if err == ErrPubExist {
-			// the added public key is existed
-			continue
+
+		// the scopes of an existing public key are replaced, and a bare entry restores its full access
+		if prefixes != nil || err == ErrPubExist {
+			err = SetScopes(c, fileid, pub, prefixes)
+			if err != nil {
+				return nil, err
+			}
 		}
-		return nil, err
 	}
 
 	statue := fmt.Sprintf("%d new pubs have been added successfully", counter)
 
 	// return an expected response
-	return positiveResponse([]byte(statue), kpri)
+	return positiveResponse([]byte(statue), signer)
 }
 
 func handleRequestD(fileid, pub []byte) error {
@@ -251,7 +323,7 @@ func handleRequestD(fileid, pub []byte) error {
 
 func handleRequestE(fileid, spub []byte,
 	epub *ecies.PublicKey,
-	kpri *ecdsa.PrivateKey) ([]byte, error) {
+	signer Signer) ([]byte, error) {
 
 	// connect database host
 	session, err := mgo.Dial("localhost")
@@ -264,24 +336,54 @@ func handleRequestE(fileid, spub []byte,
 	sud := session.DB(SupDB)
 	sad := session.DB(SaltDB)
 
-	kos, err := returnAllKeys(msd, sud, sad, fileid, spub)
+	kos, err := ReturnAllKeys(msd, sud, sad, fileid, spub)
 	if err == ErrNoAccess {
-		return negativeResponse([]byte("Permission denied"), kpri)
+		return negativeResponse([]byte("Permission denied"), signer)
 	}
 	if err == ErrNoFileid {
-		return negativeResponse([]byte("No such fileid in kdc"), kpri)
+		return negativeResponse([]byte("No such fileid in kdc"), signer)
 	}
 	if err == nil {
-		return allKeysResponse(fileid, kos, epub, kpri)
+		return allKeysResponse(fileid, kos, epub, signer)
 	}
 	return nil, err
 }
 
+func handleRequestF(fileid, spub []byte,
+	signer Signer) ([]byte, error) {
+
+	// connect database host
+	session, err := mgo.Dial("localhost")
+	if err != nil {
+		return nil, errors.New("handleRequestF: failed to connect with local host")
+	}
+	defer session.Close()
+
+	wdb := session.DB(WilDB)
+	sud := session.DB(SupDB)
+
+	// check for permissions
+	if !CheckWhitelist(wdb, fileid, spub) && !CheckSuperuser(sud, spub) {
+		return negativeResponse([]byte("Permission denied"), signer)
+	}
+
+	snap, err := TakeSnapshot(wdb, fileid)
+	if err == mgo.ErrNotFound {
+		return negativeResponse([]byte("No such fileid in kdc"), signer)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("handleRequestF: failed to take snapshot with error: %s", err.Error())
+	}
+
+	// return a signed snapshot
+	return snapshotResponse(snap, signer)
+}
+
 // 0xab, respond keys which belong to the pub
 func expectedResponse(fileid []byte,
 	keys *SubKey,
 	pub *ecies.PublicKey,
-	pri *ecdsa.PrivateKey) ([]byte, error) {
+	signer Signer) ([]byte, error) {
 
 	ty := []byte{0xab}
 
@@ -303,7 +405,7 @@ func expectedResponse(fileid []byte,
 	copy(msg[1:], c)
 
 	// sign message
-	sign, err := crypto.SignMessage(msg, pri)
+	sign, err := signer.Sign(msg)
 	if err != nil {
 		return nil, fmt.Errorf("expectedResponse: failed to sign message with error: %s", err.Error())
 	}
@@ -313,12 +415,13 @@ func expectedResponse(fileid []byte,
 		Type: ty,
 		Cora: c,
 		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
 	}
 	return proto.Marshal(rep)
 }
 
 // 0xcd respond the executing state
-func positiveResponse(state []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
+func positiveResponse(state []byte, signer Signer) ([]byte, error) {
 	ty := []byte{0xcd}
 
 	// assemble messages
@@ -327,7 +430,7 @@ func positiveResponse(state []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
 	copy(msg[1:], state)
 
 	// sign message
-	sign, err := crypto.SignMessage(msg, pri)
+	sign, err := signer.Sign(msg)
 	if err != nil {
 		return nil, fmt.Errorf("positiveResponse: failed to sign message with error: %s", err.Error())
 	}
@@ -337,12 +440,13 @@ func positiveResponse(state []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
 		Type: ty,
 		Cora: state,
 		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
 	}
 	return proto.Marshal(rep)
 }
 
 // 0x00 Reject the request with some reasons
-func negativeResponse(reason []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
+func negativeResponse(reason []byte, signer Signer) ([]byte, error) {
 	ty := []byte{0x00}
 
 	// assemble messages
@@ -351,7 +455,7 @@ func negativeResponse(reason []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
 	copy(msg[1:], reason)
 
 	// sign message
-	sign, err := crypto.SignMessage(msg, pri)
+	sign, err := signer.Sign(msg)
 	if err != nil {
 		return nil, fmt.Errorf("negativeResponse: failed to sign message with error: %s", err.Error())
 	}
@@ -361,6 +465,7 @@ func negativeResponse(reason []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
 		Type: ty,
 		Cora: reason,
 		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
 	}
 	return proto.Marshal(rep)
 }
@@ -369,7 +474,7 @@ func negativeResponse(reason []byte, pri *ecdsa.PrivateKey) ([]byte, error) {
 func allKeysResponse(fileid []byte,
 	keys []*KeyOwner,
 	pub *ecies.PublicKey,
-	pri *ecdsa.PrivateKey) ([]byte, error) {
+	signer Signer) ([]byte, error) {
 
 	ty := []byte{0xef}
 
@@ -401,7 +506,7 @@ func allKeysResponse(fileid []byte,
 	copy(msg[1+len(fileid):], eks)
 
 	// sign message
-	sign, err := crypto.SignMessage(msg, pri)
+	sign, err := signer.Sign(msg)
 	if err != nil {
 		return nil, fmt.Errorf("allKeysResponse: failed to sign message with error: %s", err.Error())
 	}
@@ -412,20 +517,60 @@ func allKeysResponse(fileid []byte,
 		Cora: fileid,
 		Keys: ras,
 		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
 	}
 	return proto.Marshal(rep)
 }
 
-// Assemble Keys list to bytes
-func EkeysToBytes(ras []*protobuf.ResponseAllkeys) (ekb []byte) {
-	for _, ek := range ras {
-		for _, ele := range ek.Pub {
-			ekb = append(ekb, ele)
-		}
+// 0xfa respond a snapshot of the whitelist
+func snapshotResponse(snap *protobuf.Snapshot, signer Signer) ([]byte, error) {
+	ty := []byte{0xfa}
 
-		for _, ele := range ek.Enk {
-			ekb = append(ekb, ele)
-		}
+	s, err := proto.Marshal(snap)
+	if err != nil {
+		return nil, errors.New("snapshotResponse: failed to marshal snapshot")
+	}
+
+	// assemble messages
+	msg := make([]byte, 1+len(s))
+	copy(msg, ty)
+	copy(msg[1:], s)
+
+	// sign message
+	sign, err := signer.Sign(msg)
+	if err != nil {
+		return nil, fmt.Errorf("snapshotResponse: failed to sign message with error: %s", err.Error())
+	}
+
+	// marshal as protocol buffer
+	rep := &protobuf.Response{
+		Type: ty,
+		Cora: s,
+		Smsg: sign,
+		Kid:  crypto.KeyID(signer.Public()),
+	}
+	return proto.Marshal(rep)
+}
+
+// ListToBytes encodes the list of a request for its signature, and each element is 4-byte length
+// prefixed, so that the boundaries between the elements are signed as well
+func ListToBytes(list [][]byte) (lb []byte) {
+	for _, ele := range list {
+		var size [4]byte
+		binary.BigEndian.PutUint32(size[:], uint32(len(ele)))
+		lb = append(lb, size[:]...)
+		lb = append(lb, ele...)
 	}
 	return
 }
+
+// Assemble Keys list to bytes
+// EkeysToBytes encodes the keys of a response for its signature as ListToBytes does, the Pub and
+// the Enk of each key are length prefixed, so that bytes cannot be moved between them
+func EkeysToBytes(ras []*protobuf.ResponseAllkeys) []byte {
+	list := make([][]byte, 0, 2*len(ras))
+	for _, ek := range ras {
+		list = append(list, ek.Pub, ek.Enk)
+	}
+	return ListToBytes(list)
+}
@@ -0,0 +1,77 @@
+// KDC rotates its signing key by endorsement: the old key signs an endorsement of the new
+// public key, and clients who trust the old key extend their trust to the new one. After
+// the rotation, the old key is retired by a revocation signed by itself. A revocation may also be
+// signed by a key which endorsed the revoked one or by a root key, and its stamp is the time since
+// which the key is revoked: now for a retired key, or the time it leaked for a compromised key, so
+// that the endorsements the thief signed since then are ignored. Endorsements and revocations are
+// published by kdc along with its public key, and clients feed them into the trust store of client package.
+
+package kdc
+
+import (
+	"crypto/ecdsa"
+	"encoding/binary"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// EndorsementMsg assembles the signed message of an endorsement: 0xe0||kid||pub||stamp
+func EndorsementMsg(kid, pub []byte, stamp int64) []byte {
+	msg := make([]byte, 1+len(kid)+len(pub)+8)
+	msg[0] = 0xe0
+	copy(msg[1:], kid)
+	copy(msg[1+len(kid):], pub)
+	binary.BigEndian.PutUint64(msg[1+len(kid)+len(pub):], uint64(stamp))
+	return msg
+}
+
+// RevocationMsg assembles the signed message of a revocation: 0xe1||kid||signer||stamp
+func RevocationMsg(kid, signer []byte, stamp int64) []byte {
+	msg := make([]byte, 1+len(kid)+len(signer)+8)
+	msg[0] = 0xe1
+	copy(msg[1:], kid)
+	copy(msg[1+len(kid):], signer)
+	binary.BigEndian.PutUint64(msg[1+len(kid)+len(signer):], uint64(stamp))
+	return msg
+}
+
+// EndorseKey returns an endorsement of the new public key signed by the current signer
+func EndorseKey(signer Signer, pub *ecdsa.PublicKey) ([]byte, error) {
+	kid := crypto.KeyID(signer.Public())
+	pk := crypto.EcdsaPubToBytes(pub, crypto.DefaultCurve)
+	stamp := time.Now().Unix()
+
+	sign, err := signer.Sign(EndorsementMsg(kid, pk, stamp))
+	if err != nil {
+		return nil, fmt.Errorf("EndorseKey: failed to sign message with error: %s", err.Error())
+	}
+
+	return proto.Marshal(&protobuf.Endorsement{
+		Kid:   kid,
+		Pub:   pk,
+		Stamp: proto.Int64(stamp),
+		Smsg:  sign,
+	})
+}
+
+// RevokeKey returns a revocation of the key identifier signed by the signer, and the key is
+// revoked since the unix time
+func RevokeKey(signer Signer, kid []byte, since int64) ([]byte, error) {
+	sid := crypto.KeyID(signer.Public())
+
+	sign, err := signer.Sign(RevocationMsg(kid, sid, since))
+	if err != nil {
+		return nil, fmt.Errorf("RevokeKey: failed to sign message with error: %s", err.Error())
+	}
+
+	return proto.Marshal(&protobuf.Revocation{
+		Kid:    kid,
+		Signer: sid,
+		Stamp:  proto.Int64(since),
+		Smsg:   sign,
+	})
+}
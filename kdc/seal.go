@@ -0,0 +1,326 @@
+// In sealed mode, KDC starts without the key-encryption key and rejects every request with a
+// "KDC is sealed" negative response. The KEK is split into Shamir shares held by operators.
+// Each operator submits the share through the admin channel, which is a local Unix socket
+// apart from the request handling, in an unseal request signed by the operator's ecdsa key:
+// UnsealRequest: 0x1e Norf = challenge||share, Smsg = signature of 0x1e||challenge||share
+// Once enough shares have been submitted, they are combined in memory and KDC is unsealed.
+// The challenge is sent by KDC when an operator connects to the admin channel, and is renewed
+// whenever the shares are combined or dropped, so that a captured request cannot be replayed
+// to unseal KDC once again.
+
+package kdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// maxFrameSize limits the size of messages on local sockets
+	maxFrameSize = 1 << 20
+
+	// ChallengeLen is the length of unseal challenges
+	ChallengeLen = 16
+)
+
+var (
+	ErrSealed       = errors.New("KDC is sealed")
+	ErrBadUnseal    = errors.New("invalid unseal request")
+	ErrBadThreshold = errors.New("invalid unseal threshold")
+	ErrBadChallenge = errors.New("unseal challenge mismatch")
+	ErrBadDigest    = errors.New("invalid digest of KEK")
+)
+
+// Sealer is implemented by key providers that may be sealed
+type Sealer interface {
+	Sealed() bool
+}
+
+// Unsealer is a KeyProvider which holds the KEK in memory only after it is unsealed
+type Unsealer struct {
+	mu        sync.Mutex
+	threshold int
+	operators map[string]bool
+	digest    []byte
+	shares    map[string][]byte
+	challenge []byte
+	kek       []byte
+}
+
+// NewUnsealer returns a sealed key provider. Only the listed operators can submit shares,
+// and the threshold must be at least 2 and at most the number of operators.
+// The combined KEK is checked against digest, its SHA3-256 hash, so that wrong shares
+// never unseal KDC with a garbage KEK
+func NewUnsealer(threshold int, operators [][]byte, digest []byte) (*Unsealer, error) {
+	if len(digest) != 32 {
+		return nil, ErrBadDigest
+	}
+
+	u := &Unsealer{
+		threshold: threshold,
+		operators: make(map[string]bool),
+		digest:    digest,
+		shares:    make(map[string][]byte),
+	}
+	for _, op := range operators {
+		u.operators[hex.EncodeToString(op)] = true
+	}
+	if threshold < 2 || threshold > len(u.operators) {
+		return nil, ErrBadThreshold
+	}
+
+	err := u.renew()
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// renew drops the submitted shares and starts a new unseal session with a fresh challenge
+func (u *Unsealer) renew() error {
+	challenge := make([]byte, ChallengeLen)
+	if _, err := io.ReadFull(rand.Reader, challenge); err != nil {
+		return err
+	}
+	u.challenge = challenge
+	u.shares = make(map[string][]byte)
+	return nil
+}
+
+// Challenge returns the challenge of the current unseal session
+func (u *Unsealer) Challenge() []byte {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.challenge == nil && u.renew() != nil {
+		return nil
+	}
+	return append([]byte{}, u.challenge...)
+}
+
+// KEK implements KeyProvider
+func (u *Unsealer) KEK() ([]byte, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.kek == nil {
+		return nil, ErrSealed
+	}
+	return u.kek, nil
+}
+
+// Sealed implements Sealer
+func (u *Unsealer) Sealed() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	return u.kek == nil
+}
+
+// Seal drops the KEK and all the submitted shares
+func (u *Unsealer) Seal() {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for i := range u.kek {
+		u.kek[i] = 0
+	}
+	u.kek = nil
+	if u.renew() != nil {
+		u.challenge = nil
+		u.shares = make(map[string][]byte)
+	}
+}
+
+// Submit adds the share of an operator for the challenge, and returns the number of shares
+// submitted so far. A later share of the same operator replaces the former one
+func (u *Unsealer) Submit(operator, challenge, share []byte) (progress int, err error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.kek != nil {
+		return u.threshold, nil
+	}
+
+	op := hex.EncodeToString(operator)
+	if !u.operators[op] {
+		return len(u.shares), ErrNoAccess
+	}
+	if len(u.challenge) != ChallengeLen || subtle.ConstantTimeCompare(challenge, u.challenge) != 1 {
+		return len(u.shares), ErrBadChallenge
+	}
+	u.shares[op] = share
+
+	if len(u.shares) < u.threshold {
+		return len(u.shares), nil
+	}
+
+	var shares [][]byte
+	for _, s := range u.shares {
+		shares = append(shares, s)
+	}
+
+	// the challenge is used up, whether or not the shares are right
+	err = u.renew()
+	if err != nil {
+		return 0, err
+	}
+
+	kek, err := crypto.CombineShares(shares)
+	if err != nil {
+		return 0, err
+	}
+	if len(kek) != KEKLen || subtle.ConstantTimeCompare(crypto.SHA3_256(kek), u.digest) != 1 {
+		return 0, crypto.ErrBadShares
+	}
+	u.kek = kek
+	return u.threshold, nil
+}
+
+// checkSealed reports whether the KEK of kdc is sealed
+func checkSealed() bool {
+	s, ok := KEK.(Sealer)
+	return ok && s.Sealed()
+}
+
+// HandleUnseal handles an unseal request, and returns the state of unsealer
+func HandleUnseal(request []byte, u *Unsealer) (state []byte, err error) {
+	req := &protobuf.Request{}
+	err = proto.Unmarshal(request, req)
+	if err != nil {
+		return nil, ErrBadUnseal
+	}
+
+	if !bytes.Equal(req.Type, []byte{0x1e}) || len(req.Norf) <= ChallengeLen {
+		return nil, ErrBadUnseal
+	}
+
+	msg := make([]byte, 1+len(req.Norf))
+	copy(msg, req.Type)
+	copy(msg[1:], req.Norf)
+	if !crypto.VerifySignNoPub(msg, req.Smsg) {
+		return nil, ErrBadUnseal
+	}
+	operator, _ := crypto.PubFromSign(msg, req.Smsg)
+
+	progress, err := u.Submit(operator, req.Norf[:ChallengeLen], req.Norf[ChallengeLen:])
+	if err != nil {
+		return nil, err
+	}
+	if !u.Sealed() {
+		return []byte("KDC has been unsealed"), nil
+	}
+	return []byte(fmt.Sprintf("%d of %d shares have been submitted", progress, u.threshold)), nil
+}
+
+// ServeAdmin accepts unseal requests on the listener, which should be a Unix socket
+// only accessible to operators. It sends the challenge first, and then reads the request.
+// Each reply is 0xcd||state or 0x00||reason
+func ServeAdmin(l net.Listener, u *Unsealer) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			if writeFrame(conn, u.Challenge()) != nil {
+				return
+			}
+
+			req, err := readFrame(conn)
+			if err != nil {
+				return
+			}
+
+			state, err := HandleUnseal(req, u)
+			if err != nil {
+				writeFrame(conn, append([]byte{0x00}, err.Error()...))
+				return
+			}
+			writeFrame(conn, append([]byte{0xcd}, state...))
+		}(conn)
+	}
+}
+
+// SubmitShare sends the unseal request made for the challenge of kdc to its admin socket,
+// and returns the state
+func SubmitShare(path string, request func(challenge []byte) ([]byte, error)) (state []byte, err error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	challenge, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(challenge) != ChallengeLen {
+		return nil, ErrBadUnseal
+	}
+
+	req, err := request(challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeFrame(conn, req)
+	if err != nil {
+		return nil, err
+	}
+
+	rep, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(rep) == 0 {
+		return nil, ErrBadUnseal
+	}
+	if rep[0] != 0xcd {
+		return nil, errors.New(string(rep[1:]))
+	}
+	return rep[1:], nil
+}
+
+// writeFrame writes a 4-byte length prefixed message
+func writeFrame(w io.Writer, msg []byte) error {
+	buf := make([]byte, 4+len(msg))
+	binary.BigEndian.PutUint32(buf, uint32(len(msg)))
+	copy(buf[4:], msg)
+	_, err := w.Write(buf)
+	return err
+}
+
+// readFrame reads a 4-byte length prefixed message
+func readFrame(r io.Reader) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+
+	n := binary.BigEndian.Uint32(size[:])
+	if n > maxFrameSize {
+		return nil, errors.New("frame is too large")
+	}
+
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,152 @@
+package kdc
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// unsealRequest plays the role of an operator
+func unsealRequest(share []byte, pri *ecdsa.PrivateKey) func([]byte) ([]byte, error) {
+	return func(challenge []byte) ([]byte, error) {
+		norf := append(append([]byte{}, challenge...), share...)
+		sign, err := crypto.SignMessage(append([]byte{0x1e}, norf...), pri)
+		if err != nil {
+			return nil, err
+		}
+		return proto.Marshal(&protobuf.Request{
+			Type: []byte{0x1e},
+			Norf: norf,
+			Smsg: sign,
+		})
+	}
+}
+
+func TestUnseal(t *testing.T) {
+	kek, _ := hex.DecodeString(testkek)
+	shares, err := crypto.SplitSecret(kek, 3, 2)
+	if err != nil {
+		panic(err)
+	}
+
+	var ops []*ecdsa.PrivateKey
+	var pubs [][]byte
+	for i := 0; i < 3; i++ {
+		pri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+		ops = append(ops, pri)
+		pubs = append(pubs, crypto.EcdsaPubToBytes(&pri.PublicKey, crypto.DefaultCurve))
+	}
+	stranger, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+
+	for _, threshold := range []int{0, 1, 4} {
+		if _, err = NewUnsealer(threshold, pubs, crypto.SHA3_256(kek)); err != ErrBadThreshold {
+			t.Errorf("threshold %d is accepted", threshold)
+		}
+	}
+
+	// the digest is mandatory
+	if _, err = NewUnsealer(2, pubs, nil); err != ErrBadDigest {
+		t.Errorf("unsealer without digest is accepted")
+	}
+
+	u, err := NewUnsealer(2, pubs, crypto.SHA3_256(kek))
+	if err != nil {
+		panic(err)
+	}
+	if _, err = u.KEK(); err != ErrSealed {
+		t.Errorf("sealed unsealer returned kek")
+	}
+
+	// kdc rejects requests while sealed
+	saved := KEK
+	KEK = u
+	defer func() { KEK = saved }()
+
+	kpri, _ := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	req, _ := hex.DecodeString(requestbuf["requestB"])
+	rep, err := ResopndToRequest(req, kpri)
+	if err != nil {
+		panic(err)
+	}
+	pb := &protobuf.Response{}
+	proto.Unmarshal(rep, pb)
+	if string(pb.Cora) != "KDC is sealed" {
+		t.Errorf("sealed kdc responded: %s", pb.Cora)
+	}
+
+	// submit shares through the admin socket
+	dir, _ := ioutil.TempDir("", "kdcadmin")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "admin.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		panic(err)
+	}
+	defer l.Close()
+	go ServeAdmin(l, u)
+
+	if _, err = SubmitShare(path, unsealRequest(shares[0], stranger)); err == nil {
+		t.Errorf("share of stranger is accepted")
+	}
+
+	// capture the request of the first operator
+	var captured []byte
+	state, err := SubmitShare(path, func(challenge []byte) ([]byte, error) {
+		req, err := unsealRequest(shares[0], ops[0])(challenge)
+		captured = req
+		return req, err
+	})
+	if err != nil {
+		panic(err)
+	}
+	if !u.Sealed() {
+		t.Errorf("unsealed with only one share")
+	}
+
+	state, err = SubmitShare(path, unsealRequest(shares[2], ops[1]))
+	if err != nil {
+		panic(err)
+	}
+	if u.Sealed() {
+		t.Errorf("failed to unseal: %s", state)
+	}
+
+	k, err := u.KEK()
+	if err != nil || hex.EncodeToString(k) != testkek {
+		t.Errorf("wrong kek is combined")
+	}
+
+	u.Seal()
+	if !u.Sealed() {
+		t.Errorf("failed to seal")
+	}
+
+	// the captured request is bound to the former challenge
+	if _, err = HandleUnseal(captured, u); err != ErrBadChallenge {
+		t.Errorf("captured unseal request is replayed")
+	}
+	if _, err = SubmitShare(path, unsealRequest(shares[1], ops[2])); err != nil {
+		panic(err)
+	}
+	if _, err = HandleUnseal(captured, u); err != ErrBadChallenge || !u.Sealed() {
+		t.Errorf("unsealed by a replayed request")
+	}
+
+	// shares of another KEK never unseal
+	other, _ := crypto.SplitSecret(crypto.SHA3_256(kek), 3, 2)
+	u.Seal()
+	challenge := u.Challenge()
+	u.Submit(pubs[0], challenge, other[0])
+	if _, err = u.Submit(pubs[1], challenge, other[1]); err != crypto.ErrBadShares || !u.Sealed() {
+		t.Errorf("unsealed by shares of another kek")
+	}
+}
@@ -0,0 +1,148 @@
+// Signer abstracts the private key of kdc which signs all the responses. LocalSigner keeps the
+// key in process, while SocketSigner asks a separate signing process through a Unix socket so
+// that the key material is isolated from the request handling process. Other signers such as
+// an HSM only need to implement the interface.
+
+// The signing process is served by ServeSigner, and each message on socket is 4-byte length
+// prefixed. The request is op||payload, and the reply is 0xcd||payload or 0x00||reason.
+// op 0x01: return the public key, the payload is empty
+// op 0x02: sign the payload by crypto.SignMessage
+
+package kdc
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"genaro-crypto/crypto"
+	"net"
+)
+
+// Signer signs messages in the same way as crypto.SignMessage,
+// so that the signatures can be verified by crypto.VerifySignature
+type Signer interface {
+	Sign(msg []byte) ([]byte, error)
+	Public() *ecdsa.PublicKey
+}
+
+var ErrSignerReply = errors.New("bad reply from signer")
+
+// LocalSigner signs messages by the private key in process
+type LocalSigner struct {
+	pri *ecdsa.PrivateKey
+}
+
+// NewLocalSigner returns an in-process signer
+func NewLocalSigner(pri *ecdsa.PrivateKey) *LocalSigner {
+	return &LocalSigner{pri}
+}
+
+// Sign implements Signer
+func (s *LocalSigner) Sign(msg []byte) ([]byte, error) {
+	return crypto.SignMessage(msg, s.pri)
+}
+
+// Public implements Signer
+func (s *LocalSigner) Public() *ecdsa.PublicKey {
+	return &s.pri.PublicKey
+}
+
+// SocketSigner signs messages through the Unix socket served by ServeSigner
+type SocketSigner struct {
+	path string
+	pub  *ecdsa.PublicKey
+}
+
+// NewSocketSigner connects the signing process and loads its public key
+func NewSocketSigner(path string) (*SocketSigner, error) {
+	s := &SocketSigner{path: path}
+
+	pk, err := s.call(0x01, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.pub = crypto.BytesToEcdsaPub(pk, crypto.DefaultCurve)
+	if s.pub == nil || s.pub.X == nil {
+		return nil, ErrSignerReply
+	}
+	return s, nil
+}
+
+// Sign implements Signer
+func (s *SocketSigner) Sign(msg []byte) ([]byte, error) {
+	sign, err := s.call(0x02, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	// never forward a signature which is not made by the expected key
+	if !crypto.VerifySignature(msg, sign, s.pub) {
+		return nil, ErrSignerReply
+	}
+	return sign, nil
+}
+
+// Public implements Signer
+func (s *SocketSigner) Public() *ecdsa.PublicKey {
+	return s.pub
+}
+
+func (s *SocketSigner) call(op byte, payload []byte) ([]byte, error) {
+	conn, err := net.Dial("unix", s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	err = writeFrame(conn, append([]byte{op}, payload...))
+	if err != nil {
+		return nil, err
+	}
+
+	rep, err := readFrame(conn)
+	if err != nil {
+		return nil, err
+	}
+	if len(rep) == 0 {
+		return nil, ErrSignerReply
+	}
+	if rep[0] != 0xcd {
+		return nil, errors.New(string(rep[1:]))
+	}
+	return rep[1:], nil
+}
+
+// ServeSigner signs messages from the listener by the private key. It runs in the signing
+// process, and the listener should be a Unix socket only accessible to kdc
+func ServeSigner(l net.Listener, pri *ecdsa.PrivateKey) error {
+	pk := crypto.EcdsaPubToBytes(&pri.PublicKey, crypto.DefaultCurve)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func(conn net.Conn) {
+			defer conn.Close()
+
+			req, err := readFrame(conn)
+			if err != nil || len(req) == 0 {
+				return
+			}
+
+			switch req[0] {
+			case 0x01:
+				writeFrame(conn, append([]byte{0xcd}, pk...))
+			case 0x02:
+				sign, err := crypto.SignMessage(req[1:], pri)
+				if err != nil {
+					writeFrame(conn, append([]byte{0x00}, err.Error()...))
+					return
+				}
+				writeFrame(conn, append([]byte{0xcd}, sign...))
+			default:
+				writeFrame(conn, append([]byte{0x00}, "unknown operation"...))
+			}
+		}(conn)
+	}
+}
@@ -0,0 +1,56 @@
+package kdc
+
+import (
+	"bytes"
+	"crypto/rand"
+	"genaro-crypto/crypto"
+	"genaro-crypto/protobuf"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+)
+
+func TestSocketSigner(t *testing.T) {
+	kpri, err := crypto.GenerateEcdsaPri(rand.Reader, crypto.DefaultCurve)
+	if err != nil {
+		panic(err)
+	}
+
+	dir, _ := ioutil.TempDir("", "kdcsigner")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "signer.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		panic(err)
+	}
+	defer l.Close()
+	go ServeSigner(l, kpri)
+
+	signer, err := NewSocketSigner(path)
+	if err != nil {
+		panic(err)
+	}
+	pk := crypto.EcdsaPubToBytes(signer.Public(), crypto.DefaultCurve)
+	if !bytes.Equal(pk, crypto.EcdsaPubToBytes(&kpri.PublicKey, crypto.DefaultCurve)) {
+		t.Errorf("wrong public key from signer")
+	}
+
+	// responses signed out of process are verified by the public key of kdc
+	rep, err := negativeResponse([]byte("Permission denied"), signer)
+	if err != nil {
+		panic(err)
+	}
+	pb := &protobuf.Response{}
+	proto.Unmarshal(rep, pb)
+	msg := append(pb.Type, pb.Cora...)
+	if !crypto.VerifySignature(msg, pb.Smsg, &kpri.PublicKey) {
+		t.Errorf("failed to verify the response signed by socket signer")
+	}
+	if !bytes.Equal(pb.Kid, crypto.KeyID(&kpri.PublicKey)) {
+		t.Errorf("wrong key identifier in response")
+	}
+}
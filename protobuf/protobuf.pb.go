@@ -10,6 +10,11 @@ It is generated from these files:
 It has these top-level messages:
 	Request
 	Response
+	Snapshot
+	Endorsement
+	Revocation
+	Enkeyvalue
+	Credential
 */
 package protobuf
 
@@ -35,6 +40,7 @@ type Request struct {
 	Enpk             []byte   `protobuf:"bytes,4,opt,name=enpk" json:"enpk,omitempty"`
 	List             [][]byte `protobuf:"bytes,5,rep,name=list" json:"list,omitempty"`
 	Smsg             []byte   `protobuf:"bytes,6,req,name=smsg" json:"smsg,omitempty"`
+	Cred             [][]byte `protobuf:"bytes,7,rep,name=cred" json:"cred,omitempty"`
 	XXX_unrecognized []byte   `json:"-"`
 }
 
@@ -85,11 +91,19 @@ func (m *Request) GetSmsg() []byte {
 	return nil
 }
 
+func (m *Request) GetCred() [][]byte {
+	if m != nil {
+		return m.Cred
+	}
+	return nil
+}
+
 type Response struct {
 	Type             []byte             `protobuf:"bytes,1,req,name=type" json:"type,omitempty"`
 	Cora             []byte             `protobuf:"bytes,2,req,name=cora" json:"cora,omitempty"`
 	Keys             []*ResponseAllkeys `protobuf:"bytes,3,rep,name=keys" json:"keys,omitempty"`
 	Smsg             []byte             `protobuf:"bytes,4,req,name=smsg" json:"smsg,omitempty"`
+	Kid              []byte             `protobuf:"bytes,5,opt,name=kid" json:"kid,omitempty"`
 	XXX_unrecognized []byte             `json:"-"`
 }
 
@@ -126,6 +140,13 @@ func (m *Response) GetSmsg() []byte {
 	return nil
 }
 
+func (m *Response) GetKid() []byte {
+	if m != nil {
+		return m.Kid
+	}
+	return nil
+}
+
 type ResponseAllkeys struct {
 	Pub              []byte `protobuf:"bytes,1,req,name=pub" json:"pub,omitempty"`
 	Enk              []byte `protobuf:"bytes,2,req,name=enk" json:"enk,omitempty"`
@@ -151,27 +172,442 @@ func (m *ResponseAllkeys) GetEnk() []byte {
 	return nil
 }
 
+type Snapshot struct {
+	Fileid           []byte            `protobuf:"bytes,1,req,name=fileid" json:"fileid,omitempty"`
+	Owner            []byte            `protobuf:"bytes,2,req,name=owner" json:"owner,omitempty"`
+	Members          []*SnapshotMember `protobuf:"bytes,3,rep,name=members" json:"members,omitempty"`
+	Epoch            *uint64           `protobuf:"varint,4,req,name=epoch" json:"epoch,omitempty"`
+	Version          *uint64           `protobuf:"varint,5,req,name=version" json:"version,omitempty"`
+	Stamp            *int64            `protobuf:"varint,6,req,name=stamp" json:"stamp,omitempty"`
+	Policy           *string           `protobuf:"bytes,7,opt,name=policy" json:"policy,omitempty"`
+	Issuers          [][]byte          `protobuf:"bytes,8,rep,name=issuers" json:"issuers,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
+}
+
+func (m *Snapshot) Reset()                    { *m = Snapshot{} }
+func (m *Snapshot) String() string            { return proto.CompactTextString(m) }
+func (*Snapshot) ProtoMessage()               {}
+func (*Snapshot) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2} }
+
+func (m *Snapshot) GetFileid() []byte {
+	if m != nil {
+		return m.Fileid
+	}
+	return nil
+}
+
+func (m *Snapshot) GetOwner() []byte {
+	if m != nil {
+		return m.Owner
+	}
+	return nil
+}
+
+func (m *Snapshot) GetMembers() []*SnapshotMember {
+	if m != nil {
+		return m.Members
+	}
+	return nil
+}
+
+func (m *Snapshot) GetEpoch() uint64 {
+	if m != nil && m.Epoch != nil {
+		return *m.Epoch
+	}
+	return 0
+}
+
+func (m *Snapshot) GetVersion() uint64 {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return 0
+}
+
+func (m *Snapshot) GetStamp() int64 {
+	if m != nil && m.Stamp != nil {
+		return *m.Stamp
+	}
+	return 0
+}
+
+func (m *Snapshot) GetPolicy() string {
+	if m != nil && m.Policy != nil {
+		return *m.Policy
+	}
+	return ""
+}
+
+func (m *Snapshot) GetIssuers() [][]byte {
+	if m != nil {
+		return m.Issuers
+	}
+	return nil
+}
+
+type SnapshotMember struct {
+	Pub              []byte  `protobuf:"bytes,1,req,name=pub" json:"pub,omitempty"`
+	Role             *uint32 `protobuf:"varint,2,req,name=role" json:"role,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *SnapshotMember) Reset()                    { *m = SnapshotMember{} }
+func (m *SnapshotMember) String() string            { return proto.CompactTextString(m) }
+func (*SnapshotMember) ProtoMessage()               {}
+func (*SnapshotMember) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{2, 0} }
+
+func (m *SnapshotMember) GetPub() []byte {
+	if m != nil {
+		return m.Pub
+	}
+	return nil
+}
+
+func (m *SnapshotMember) GetRole() uint32 {
+	if m != nil && m.Role != nil {
+		return *m.Role
+	}
+	return 0
+}
+
+type Endorsement struct {
+	Kid              []byte `protobuf:"bytes,1,req,name=kid" json:"kid,omitempty"`
+	Pub              []byte `protobuf:"bytes,2,req,name=pub" json:"pub,omitempty"`
+	Stamp            *int64 `protobuf:"varint,3,req,name=stamp" json:"stamp,omitempty"`
+	Smsg             []byte `protobuf:"bytes,4,req,name=smsg" json:"smsg,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *Endorsement) Reset()                    { *m = Endorsement{} }
+func (m *Endorsement) String() string            { return proto.CompactTextString(m) }
+func (*Endorsement) ProtoMessage()               {}
+func (*Endorsement) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{3} }
+
+func (m *Endorsement) GetKid() []byte {
+	if m != nil {
+		return m.Kid
+	}
+	return nil
+}
+
+func (m *Endorsement) GetPub() []byte {
+	if m != nil {
+		return m.Pub
+	}
+	return nil
+}
+
+func (m *Endorsement) GetStamp() int64 {
+	if m != nil && m.Stamp != nil {
+		return *m.Stamp
+	}
+	return 0
+}
+
+func (m *Endorsement) GetSmsg() []byte {
+	if m != nil {
+		return m.Smsg
+	}
+	return nil
+}
+
+type Revocation struct {
+	Kid              []byte `protobuf:"bytes,1,req,name=kid" json:"kid,omitempty"`
+	Signer           []byte `protobuf:"bytes,2,req,name=signer" json:"signer,omitempty"`
+	Stamp            *int64 `protobuf:"varint,3,req,name=stamp" json:"stamp,omitempty"`
+	Smsg             []byte `protobuf:"bytes,4,req,name=smsg" json:"smsg,omitempty"`
+	XXX_unrecognized []byte `json:"-"`
+}
+
+func (m *Revocation) Reset()                    { *m = Revocation{} }
+func (m *Revocation) String() string            { return proto.CompactTextString(m) }
+func (*Revocation) ProtoMessage()               {}
+func (*Revocation) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{4} }
+
+func (m *Revocation) GetKid() []byte {
+	if m != nil {
+		return m.Kid
+	}
+	return nil
+}
+
+func (m *Revocation) GetSigner() []byte {
+	if m != nil {
+		return m.Signer
+	}
+	return nil
+}
+
+func (m *Revocation) GetStamp() int64 {
+	if m != nil && m.Stamp != nil {
+		return *m.Stamp
+	}
+	return 0
+}
+
+func (m *Revocation) GetSmsg() []byte {
+	if m != nil {
+		return m.Smsg
+	}
+	return nil
+}
+
+type Enkeyvalue struct {
+	Version          *uint32  `protobuf:"varint,1,req,name=version" json:"version,omitempty"`
+	Suite            *uint32  `protobuf:"varint,2,req,name=suite" json:"suite,omitempty"`
+	Epoch            *uint64  `protobuf:"varint,3,opt,name=epoch" json:"epoch,omitempty"`
+	Pub              []byte   `protobuf:"bytes,4,req,name=pub" json:"pub,omitempty"`
+	Ssek             []byte   `protobuf:"bytes,5,opt,name=ssek" json:"ssek,omitempty"`
+	Ekey             []byte   `protobuf:"bytes,6,req,name=ekey" json:"ekey,omitempty"`
+	Evalue           []byte   `protobuf:"bytes,7,req,name=evalue" json:"evalue,omitempty"`
+	Ssep             [][]byte `protobuf:"bytes,8,rep,name=ssep" json:"ssep,omitempty"`
+	Ssev             []byte   `protobuf:"bytes,9,opt,name=ssev" json:"ssev,omitempty"`
+	Ssef             [][]byte `protobuf:"bytes,10,rep,name=ssef" json:"ssef,omitempty"`
+	Ssem             []byte   `protobuf:"bytes,11,opt,name=ssem" json:"ssem,omitempty"`
+	Ore              []byte   `protobuf:"bytes,12,opt,name=ore" json:"ore,omitempty"`
+	He               []byte   `protobuf:"bytes,13,opt,name=he" json:"he,omitempty"`
+	Epath            [][]byte `protobuf:"bytes,14,rep,name=epath" json:"epath,omitempty"`
+	Ssec             []byte   `protobuf:"bytes,15,opt,name=ssec" json:"ssec,omitempty"`
+	XXX_unrecognized []byte   `json:"-"`
+}
+
+func (m *Enkeyvalue) Reset()                    { *m = Enkeyvalue{} }
+func (m *Enkeyvalue) String() string            { return proto.CompactTextString(m) }
+func (*Enkeyvalue) ProtoMessage()               {}
+func (*Enkeyvalue) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{5} }
+
+func (m *Enkeyvalue) GetVersion() uint32 {
+	if m != nil && m.Version != nil {
+		return *m.Version
+	}
+	return 0
+}
+
+func (m *Enkeyvalue) GetSuite() uint32 {
+	if m != nil && m.Suite != nil {
+		return *m.Suite
+	}
+	return 0
+}
+
+func (m *Enkeyvalue) GetEpoch() uint64 {
+	if m != nil && m.Epoch != nil {
+		return *m.Epoch
+	}
+	return 0
+}
+
+func (m *Enkeyvalue) GetPub() []byte {
+	if m != nil {
+		return m.Pub
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetSsek() []byte {
+	if m != nil {
+		return m.Ssek
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetEkey() []byte {
+	if m != nil {
+		return m.Ekey
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetEvalue() []byte {
+	if m != nil {
+		return m.Evalue
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetSsep() [][]byte {
+	if m != nil {
+		return m.Ssep
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetSsev() []byte {
+	if m != nil {
+		return m.Ssev
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetSsef() [][]byte {
+	if m != nil {
+		return m.Ssef
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetSsem() []byte {
+	if m != nil {
+		return m.Ssem
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetOre() []byte {
+	if m != nil {
+		return m.Ore
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetHe() []byte {
+	if m != nil {
+		return m.He
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetEpath() [][]byte {
+	if m != nil {
+		return m.Epath
+	}
+	return nil
+}
+
+func (m *Enkeyvalue) GetSsec() []byte {
+	if m != nil {
+		return m.Ssec
+	}
+	return nil
+}
+
+type Credential struct {
+	Pub              []byte                 `protobuf:"bytes,1,req,name=pub" json:"pub,omitempty"`
+	Attrs            []*CredentialAttribute `protobuf:"bytes,2,rep,name=attrs" json:"attrs,omitempty"`
+	Expiry           *int64                 `protobuf:"varint,3,req,name=expiry" json:"expiry,omitempty"`
+	Kid              []byte                 `protobuf:"bytes,4,req,name=kid" json:"kid,omitempty"`
+	Smsg             []byte                 `protobuf:"bytes,5,req,name=smsg" json:"smsg,omitempty"`
+	XXX_unrecognized []byte                 `json:"-"`
+}
+
+func (m *Credential) Reset()                    { *m = Credential{} }
+func (m *Credential) String() string            { return proto.CompactTextString(m) }
+func (*Credential) ProtoMessage()               {}
+func (*Credential) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6} }
+
+func (m *Credential) GetPub() []byte {
+	if m != nil {
+		return m.Pub
+	}
+	return nil
+}
+
+func (m *Credential) GetAttrs() []*CredentialAttribute {
+	if m != nil {
+		return m.Attrs
+	}
+	return nil
+}
+
+func (m *Credential) GetExpiry() int64 {
+	if m != nil && m.Expiry != nil {
+		return *m.Expiry
+	}
+	return 0
+}
+
+func (m *Credential) GetKid() []byte {
+	if m != nil {
+		return m.Kid
+	}
+	return nil
+}
+
+func (m *Credential) GetSmsg() []byte {
+	if m != nil {
+		return m.Smsg
+	}
+	return nil
+}
+
+type CredentialAttribute struct {
+	Name             *string `protobuf:"bytes,1,req,name=name" json:"name,omitempty"`
+	Value            *string `protobuf:"bytes,2,req,name=value" json:"value,omitempty"`
+	XXX_unrecognized []byte  `json:"-"`
+}
+
+func (m *CredentialAttribute) Reset()                    { *m = CredentialAttribute{} }
+func (m *CredentialAttribute) String() string            { return proto.CompactTextString(m) }
+func (*CredentialAttribute) ProtoMessage()               {}
+func (*CredentialAttribute) Descriptor() ([]byte, []int) { return fileDescriptor0, []int{6, 0} }
+
+func (m *CredentialAttribute) GetName() string {
+	if m != nil && m.Name != nil {
+		return *m.Name
+	}
+	return ""
+}
+
+func (m *CredentialAttribute) GetValue() string {
+	if m != nil && m.Value != nil {
+		return *m.Value
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*Request)(nil), "protobuf.request")
 	proto.RegisterType((*Response)(nil), "protobuf.response")
 	proto.RegisterType((*ResponseAllkeys)(nil), "protobuf.response.allkeys")
+	proto.RegisterType((*Snapshot)(nil), "protobuf.snapshot")
+	proto.RegisterType((*SnapshotMember)(nil), "protobuf.snapshot.member")
+	proto.RegisterType((*Endorsement)(nil), "protobuf.endorsement")
+	proto.RegisterType((*Revocation)(nil), "protobuf.revocation")
+	proto.RegisterType((*Enkeyvalue)(nil), "protobuf.enkeyvalue")
+	proto.RegisterType((*Credential)(nil), "protobuf.credential")
+	proto.RegisterType((*CredentialAttribute)(nil), "protobuf.credential.attribute")
 }
 
 func init() { proto.RegisterFile("protobuf.proto", fileDescriptor0) }
 
 var fileDescriptor0 = []byte{
-	// 203 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x8f, 0xbf, 0x4e, 0xc7, 0x20,
-	0x14, 0x85, 0xc3, 0x0f, 0xb4, 0x0d, 0x1a, 0x63, 0x98, 0x48, 0x27, 0xd2, 0xa9, 0x8b, 0x0c, 0x3e,
-	0x4a, 0xdf, 0xa0, 0x35, 0xb7, 0xc6, 0xb4, 0x02, 0x02, 0x1d, 0x3a, 0xfa, 0x30, 0xbe, 0xa7, 0x39,
-	0xf4, 0x8f, 0x93, 0xdb, 0xc7, 0x97, 0xc3, 0x3d, 0xf7, 0xca, 0xa7, 0x10, 0x7d, 0xf6, 0xe3, 0x3a,
-	0xd9, 0x02, 0xaa, 0x3e, 0xdf, 0xed, 0x37, 0x93, 0x55, 0xa4, 0xaf, 0x95, 0x52, 0x56, 0x4a, 0x8a,
-	0xbc, 0x05, 0xd2, 0xcc, 0xdc, 0xba, 0xc7, 0xbe, 0x30, 0x9c, 0xf3, 0x71, 0xd2, 0xb7, 0xdd, 0x81,
-	0xe1, 0x92, 0xf3, 0x4e, 0x73, 0xc3, 0xe0, 0xc0, 0x70, 0xe4, 0xc2, 0xac, 0xc5, 0xee, 0xc0, 0x70,
-	0xcb, 0x47, 0xca, 0xfa, 0xce, 0x70, 0x38, 0x70, 0xf9, 0xfb, 0x99, 0xde, 0xf5, 0xfd, 0x3e, 0x0f,
-	0xdc, 0xfe, 0x30, 0x59, 0x47, 0x4a, 0xc1, 0xbb, 0x44, 0xff, 0x2d, 0xf1, 0xe6, 0xe3, 0x70, 0x2e,
-	0x01, 0x56, 0x56, 0x8a, 0x99, 0xb6, 0xa4, 0xb9, 0xe1, 0xdd, 0xc3, 0x6b, 0x63, 0xaf, 0x0b, 0xcf,
-	0x49, 0x76, 0x58, 0x16, 0x24, 0xfa, 0x92, 0xbb, 0x8a, 0xc5, 0x5f, 0x71, 0xf3, 0x22, 0xab, 0x23,
-	0xa4, 0x9e, 0x25, 0x0f, 0xeb, 0x78, 0xb4, 0x02, 0x61, 0xc8, 0xcd, 0x47, 0x27, 0xf0, 0x37, 0x00,
-	0x00, 0xff, 0xff, 0xb9, 0x90, 0xab, 0x34, 0x46, 0x01, 0x00, 0x00,
+	// 594 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x94, 0x54, 0xbb, 0x6e, 0xdb, 0x40,
+	0x10, 0x04, 0x1f, 0x12, 0xa5, 0xf5, 0x23, 0x01, 0x11, 0x18, 0x17, 0x15, 0x81, 0xc0, 0xca, 0x4d,
+	0x58, 0xe4, 0xf1, 0x23, 0x6c, 0xd3, 0x84, 0xa2, 0x56, 0xd6, 0x81, 0xe4, 0x1d, 0x73, 0x77, 0x54,
+	0xc2, 0x0f, 0xf1, 0xb7, 0xa4, 0x4d, 0x9b, 0xbf, 0x0a, 0xf6, 0x1e, 0xa4, 0x8d, 0x38, 0x45, 0xba,
+	0x99, 0xf1, 0xee, 0x7a, 0x66, 0xf7, 0x28, 0xb8, 0x1d, 0x94, 0x34, 0xf2, 0x30, 0x9e, 0x4a, 0x0b,
+	0xf2, 0x4d, 0xe0, 0xc5, 0x63, 0x04, 0x99, 0xc2, 0x6f, 0x23, 0x6a, 0x93, 0xe7, 0x90, 0x9a, 0x69,
+	0x40, 0x16, 0xed, 0xe3, 0xfb, 0xeb, 0xca, 0x62, 0xd2, 0x84, 0x54, 0x27, 0x16, 0x3b, 0x8d, 0x30,
+	0x69, 0x5a, 0x48, 0xc1, 0x92, 0x7d, 0x44, 0x1a, 0x61, 0xd2, 0x50, 0x0c, 0x2d, 0x4b, 0x9d, 0x46,
+	0x98, 0xb4, 0x8e, 0x6b, 0xc3, 0x56, 0xfb, 0x84, 0x34, 0xc2, 0xb6, 0xb7, 0xd7, 0x0f, 0x6c, 0xed,
+	0xe6, 0x11, 0x26, 0xad, 0x51, 0x78, 0x64, 0x99, 0xab, 0x23, 0x5c, 0xfc, 0x8c, 0x60, 0xa3, 0x50,
+	0x0f, 0x52, 0x68, 0xfc, 0x97, 0xb1, 0x46, 0xaa, 0x3a, 0x18, 0x23, 0x9c, 0x97, 0x90, 0xb6, 0x38,
+	0x69, 0x96, 0xec, 0x93, 0xfb, 0xab, 0x0f, 0xbb, 0x72, 0x4e, 0x1d, 0x26, 0x95, 0x75, 0xd7, 0x51,
+	0x45, 0x65, 0xeb, 0x66, 0x33, 0xe9, 0x13, 0x33, 0xaf, 0x21, 0x69, 0xf9, 0x91, 0xad, 0x6c, 0x0e,
+	0x82, 0xbb, 0xf7, 0x90, 0xf9, 0x36, 0xfa, 0xe3, 0x30, 0x1e, 0xbc, 0x0f, 0x82, 0xa4, 0xa0, 0x68,
+	0xbd, 0x0b, 0x82, 0xc5, 0x63, 0x0c, 0x1b, 0x2d, 0xea, 0x41, 0x9f, 0xa5, 0xc9, 0xef, 0x60, 0x7d,
+	0xe2, 0x1d, 0xf2, 0xa3, 0xef, 0xf1, 0x2c, 0x7f, 0x03, 0x2b, 0xf9, 0x5d, 0xa0, 0xf2, 0x8d, 0x8e,
+	0xe4, 0x1f, 0x21, 0xeb, 0xb1, 0x3f, 0xa0, 0x0a, 0x11, 0xde, 0x2e, 0x11, 0xc2, 0xc8, 0xd2, 0x55,
+	0x54, 0xa1, 0x92, 0x46, 0xe1, 0x20, 0x9b, 0xb3, 0x4d, 0x91, 0x56, 0x8e, 0xe4, 0x0c, 0xb2, 0x0b,
+	0x2a, 0xcd, 0xa5, 0x60, 0x2b, 0xab, 0x07, 0x4a, 0xf5, 0xda, 0xd4, 0xfd, 0x60, 0x4f, 0x90, 0x54,
+	0x8e, 0x90, 0xd1, 0x41, 0x76, 0xbc, 0x99, 0x58, 0xb6, 0x8f, 0xee, 0xb7, 0x95, 0x67, 0x34, 0x87,
+	0x6b, 0x3d, 0x92, 0xa5, 0x8d, 0x3d, 0x4f, 0xa0, 0xbb, 0x12, 0xd6, 0xce, 0xc2, 0x0b, 0x5b, 0xc9,
+	0x21, 0x55, 0xb2, 0x43, 0x9b, 0xee, 0xa6, 0xb2, 0xb8, 0xf8, 0x02, 0x57, 0x28, 0x8e, 0x52, 0x69,
+	0xec, 0x51, 0x98, 0xb0, 0x67, 0xdf, 0xd4, 0xf2, 0x63, 0x18, 0x13, 0x2f, 0x63, 0x66, 0xab, 0xc9,
+	0x53, 0xab, 0x2f, 0x5c, 0xad, 0xf8, 0x0a, 0xa0, 0xf0, 0x22, 0x9b, 0xda, 0x50, 0xc4, 0xbf, 0x67,
+	0xdf, 0xc1, 0x5a, 0xf3, 0x87, 0x65, 0xe1, 0x9e, 0xfd, 0xc7, 0x7f, 0xf8, 0x15, 0x03, 0xa0, 0x68,
+	0x71, 0xba, 0xd4, 0xdd, 0x88, 0x4f, 0xf7, 0x1b, 0xd9, 0x90, 0xcf, 0xf6, 0x3b, 0x72, 0x13, 0xc2,
+	0x3b, 0xb2, 0x5c, 0x89, 0x3e, 0x9a, 0xf9, 0x4a, 0x3e, 0x72, 0xfa, 0x6c, 0x73, 0x5a, 0x63, 0xeb,
+	0xdf, 0x9f, 0xc5, 0xa4, 0x61, 0x8b, 0x53, 0xf8, 0x66, 0x08, 0x53, 0x20, 0xb4, 0x4e, 0x58, 0xe6,
+	0x02, 0x39, 0xe6, 0xfb, 0x07, 0x7f, 0x2c, 0x8b, 0xbd, 0x76, 0x61, 0xdb, 0x79, 0xe6, 0xc5, 0x6b,
+	0x27, 0x06, 0x73, 0xdd, 0xc9, 0x6b, 0x3d, 0xbb, 0x9a, 0xeb, 0x7a, 0x72, 0x28, 0x15, 0xb2, 0x6b,
+	0x2b, 0x11, 0xcc, 0x6f, 0x21, 0x3e, 0x23, 0xbb, 0xb1, 0x42, 0x7c, 0xf6, 0xc9, 0x6a, 0x73, 0x66,
+	0xb7, 0x76, 0x94, 0x23, 0x7e, 0x56, 0xc3, 0x5e, 0xcd, 0xb3, 0x9a, 0xe2, 0x77, 0x04, 0x40, 0x1f,
+	0x37, 0x0a, 0xc3, 0xeb, 0xee, 0x85, 0x67, 0xf3, 0x09, 0x56, 0xb5, 0x31, 0x4a, 0xb3, 0xd8, 0xbe,
+	0xfe, 0x77, 0xcb, 0xeb, 0x5f, 0xda, 0x4a, 0xaa, 0xe0, 0x87, 0xd1, 0x60, 0xe5, 0x8a, 0xed, 0x2a,
+	0x7e, 0x0c, 0x5c, 0x4d, 0xfe, 0x88, 0x9e, 0x85, 0x57, 0x90, 0x2e, 0xaf, 0x20, 0xdc, 0x75, 0xb5,
+	0xdc, 0x75, 0xf7, 0x19, 0xb6, 0xf3, 0x44, 0x2a, 0x10, 0x75, 0xef, 0x7e, 0x68, 0xb6, 0x95, 0xc5,
+	0x94, 0xcf, 0x2d, 0x3a, 0xb6, 0xa2, 0x23, 0x7f, 0x06, 0x00, 0x70, 0x03, 0x3a, 0x35, 0x52, 0x05,
+	0x00, 0x00,
 }